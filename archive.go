@@ -0,0 +1,279 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Used to add the file or directory at src to the tar archive under the given
+// name. File modes, ownership, symlinks and modification times are preserved.
+func tarPath(tw *tar.Writer, src, name string) error {
+	return filepath.WalkDir(src, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, filename)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %v", filename, err)
+		}
+		return writeTarEntry(tw, filename, path.Join(name, filepath.ToSlash(rel)), info)
+	})
+}
+
+// Used to write a single file, directory or symlink from disk to the tar archive.
+func writeTarEntry(tw *tar.Writer, filename, name string, info fs.FileInfo) error {
+	header, err := tarHeader(filename, name, info)
+	if err != nil {
+		return err
+	}
+	return writeTarHeader(tw, filename, header)
+}
+
+// Used to create the tar header for a file, directory or symlink on disk,
+// named within the archive by name.
+func tarHeader(filename, name string, info fs.FileInfo) (*tar.Header, error) {
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		var err error
+		link, err = os.Readlink(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read link %s: %v", filename, err)
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tar header for %s: %v", filename, err)
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	return header, nil
+}
+
+// Used to write the header to the tar archive, followed by the content of
+// filename when the header is for a regular file.
+func writeTarHeader(tw *tar.Writer, filename string, header *tar.Header) error {
+	err := tw.WriteHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %v", header.Name, err)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", filename, err)
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	if err != nil {
+		return fmt.Errorf("failed to write tar body for %s: %v", header.Name, err)
+	}
+	return nil
+}
+
+// Used to add every file and directory of fsys to the tar archive under the
+// given name. Entries which are neither regular files nor directories are skipped.
+func tarFS(tw *tar.Writer, fsys fs.FS, name string) error {
+	return fs.WalkDir(fsys, ".", func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %v", filename, err)
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("failed to create tar header for %s: %v", filename, err)
+		}
+		header.Name = path.Join(name, filename)
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %v", header.Name, err)
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		f, err := fsys.Open(filename)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", filename, err)
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		if err != nil {
+			return fmt.Errorf("failed to write tar body for %s: %v", header.Name, err)
+		}
+		return nil
+	})
+}
+
+// Used to stream a tar archive produced by write through a pipe, so that the
+// archive never needs to be held in memory.
+func streamTar(write func(tw *tar.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := write(tw)
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// Used to extract a tar archive, as returned when copying from a container, to
+// dst. Each entry is rooted at the base name of the copied path, which is
+// replaced with dst. Ownership is restored where the current user is permitted.
+func extractTar(r io.Reader, dst string) error {
+	type dirTimes struct {
+		path    string
+		modTime time.Time
+	}
+	dirs := make([]dirTimes, 0)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %v", err)
+		}
+		target, err := extractPath(dst, header.Name)
+		if err != nil {
+			return err
+		}
+		err = checkExtractParents(dst, target)
+		if err != nil {
+			return err
+		}
+		// An existing symlink is replaced rather than written through.
+		info, err := os.Lstat(target)
+		if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			err = os.Remove(target)
+			if err != nil {
+				return fmt.Errorf("failed to replace symlink %s: %v", target, err)
+			}
+		}
+		mode := header.FileInfo().Mode()
+		err = os.MkdirAll(filepath.Dir(target), 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create directory for %s: %v", target, err)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o755)
+			dirs = append(dirs, dirTimes{target, header.ModTime})
+		case tar.TypeReg:
+			err = extractFile(tr, target, mode)
+		case tar.TypeSymlink:
+			_ = os.Remove(target)
+			err = os.Symlink(header.Linkname, target)
+		case tar.TypeLink:
+			var linkTarget string
+			linkTarget, err = extractPath(dst, header.Linkname)
+			if err == nil {
+				err = checkExtractParents(dst, linkTarget)
+			}
+			if err == nil {
+				_ = os.Remove(target)
+				err = os.Link(linkTarget, target)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %v", header.Name, err)
+		}
+		// Restoring ownership requires privileges the caller may not have,
+		// in which case the files are left owned by the current user.
+		_ = os.Lchown(target, header.Uid, header.Gid)
+		if header.Typeflag == tar.TypeSymlink {
+			continue
+		}
+		err = os.Chmod(target, mode.Perm()|mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
+		if err != nil {
+			return fmt.Errorf("failed to set mode of %s: %v", target, err)
+		}
+		if header.Typeflag != tar.TypeDir {
+			_ = os.Chtimes(target, header.ModTime, header.ModTime)
+		}
+	}
+	// Directory times are set last, as extracting their contents updates them.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Chtimes(dirs[i].path, dirs[i].modTime, dirs[i].modTime)
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, mode fs.FileMode) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Used to reject paths under dst which pass through a symlink, such as an entry
+// "a/passwd" following a symlink "a -> /etc". The check on the entry's name is
+// lexical, so without this a symlink could be used to write outside of dst.
+func checkExtractParents(dst, target string) error {
+	rel, err := filepath.Rel(dst, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+	parent := dst
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		parent = filepath.Join(parent, part)
+		info, err := os.Lstat(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("tar entry %s is outside of the destination: %s is a symlink", target, parent)
+		}
+	}
+	return nil
+}
+
+// Used to map an archive entry name to a path under dst, rejecting entries
+// which would be written outside of dst.
+func extractPath(dst, name string) (string, error) {
+	name = path.Clean("/" + name)
+	_, rel, _ := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	target := filepath.Join(dst, filepath.FromSlash(rel))
+	within, err := filepath.Rel(dst, target)
+	if err != nil || within == ".." || strings.HasPrefix(within, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("tar entry %s is outside of the destination", name)
+	}
+	return target, nil
+}
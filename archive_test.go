@@ -0,0 +1,139 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTarPath_WhenExtracted_PreservesModesAndSymlinks(t *testing.T) {
+	src := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(src, "bin"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "bin", "entrypoint.sh"), []byte("#!/bin/sh"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "config.yaml"), []byte("port: 80"), 0o600))
+	assert.Nil(t, os.Symlink("bin/entrypoint.sh", filepath.Join(src, "start")))
+
+	content := streamTar(func(tw *tar.Writer) error {
+		return tarPath(tw, src, "app")
+	})
+	defer content.Close()
+	dst := filepath.Join(t.TempDir(), "out")
+	err := extractTar(content, dst)
+	assert.Nil(t, err)
+
+	info, err := os.Stat(filepath.Join(dst, "bin", "entrypoint.sh"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dst, "config.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	link, err := os.Readlink(filepath.Join(dst, "start"))
+	assert.Nil(t, err)
+	assert.Equal(t, "bin/entrypoint.sh", link)
+}
+
+func TestTarFS_GivenMapFS_WritesEntriesUnderName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"seed/data.sql": {Data: []byte("SELECT 1;"), Mode: 0o644},
+	}
+
+	content := streamTar(func(tw *tar.Writer) error {
+		return tarFS(tw, fsys, "initdb")
+	})
+	defer content.Close()
+	names := make([]string, 0)
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"initdb/", "initdb/seed/", "initdb/seed/data.sql"}, names)
+}
+
+func TestExtractTar_GivenEntryOutsideDestination_DoesNotEscape(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "app/../../escaped", Mode: 0o644}))
+	assert.Nil(t, tw.Close())
+
+	root := t.TempDir()
+	dst := filepath.Join(root, "out")
+	err := extractTar(buf, dst)
+
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(root, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExtractTar_GivenEntryBehindSymlink_ReturnsError(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(root, "outside")
+	assert.Nil(t, os.Mkdir(outside, 0o755))
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "app/etc", Linkname: outside}))
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "app/etc/passwd", Mode: 0o644, Size: 4}))
+	_, err := tw.Write([]byte("root"))
+	assert.Nil(t, err)
+	assert.Nil(t, tw.Close())
+
+	err = extractTar(buf, filepath.Join(root, "out"))
+
+	assert.ErrorContains(t, err, "is a symlink")
+	_, err = os.Stat(filepath.Join(outside, "passwd"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExtractTar_GivenExistingSymlink_ReplacesRatherThanWritesThrough(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(root, "outside.txt")
+	assert.Nil(t, os.WriteFile(outside, []byte("keep"), 0o644))
+	dst := filepath.Join(root, "out")
+	assert.Nil(t, os.Mkdir(dst, 0o755))
+	assert.Nil(t, os.Symlink(outside, filepath.Join(dst, "config.txt")))
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "app/config.txt", Mode: 0o644, Size: 3}))
+	_, err := tw.Write([]byte("new"))
+	assert.Nil(t, err)
+	assert.Nil(t, tw.Close())
+
+	err = extractTar(buf, dst)
+
+	assert.Nil(t, err)
+	data, err := os.ReadFile(outside)
+	assert.Nil(t, err)
+	assert.Equal(t, "keep", string(data))
+	data, err = os.ReadFile(filepath.Join(dst, "config.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestExtractTar_GivenHardlinkThroughSymlink_ReturnsError(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(root, "outside")
+	assert.Nil(t, os.Mkdir(outside, 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o600))
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "app/lib", Linkname: outside}))
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeLink, Name: "app/secret", Linkname: "app/lib/secret"}))
+	assert.Nil(t, tw.Close())
+	dst := filepath.Join(root, "out")
+
+	err := extractTar(buf, dst)
+
+	assert.ErrorContains(t, err, "is a symlink")
+	_, err = os.Lstat(filepath.Join(dst, "secret"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,256 @@
+// Package auth is used to find the registry credentials configured for the
+// Docker CLI, in the same way as "docker pull".
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// DockerHubAddress is the server address which credentials for Docker Hub are
+// stored under.
+const DockerHubAddress = "https://index.docker.io/v1/"
+
+// Used as the username by credential helpers which return an identity token.
+const tokenUsername = "<token>"
+
+// Config is the registry credentials configuration of the Docker CLI, read
+// from its config.json file.
+type Config struct {
+	// Auths are the credentials stored in the config file, keyed by registry.
+	Auths map[string]Entry `json:"auths"`
+	// CredsStore is the name of the credential helper which stores the
+	// credentials of every registry, such as "desktop" for docker-credential-desktop.
+	CredsStore string `json:"credsStore,omitempty"`
+	// CredHelpers are the names of the credential helpers used for specific
+	// registries, which take precedence over CredsStore.
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+}
+
+// Entry is the credentials of a single registry stored in the config file.
+type Entry struct {
+	// Auth is the base64 encoding of "username:password".
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// DefaultConfigPath is used to get the path of the Docker CLI's config file,
+// which is in the directory set by DOCKER_CONFIG, or ~/.docker by default.
+func DefaultConfigPath() string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// LoadConfig is used to read the config file at the specified path. If the
+// path is empty, the default path is used. A missing config file is treated
+// as having no credentials.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		path = DefaultConfigPath()
+	}
+	config := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker config: %v", err)
+	}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docker config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Credentials is used to get the credentials of the given registry, such as
+// "registry.example.com:5000" or "docker.io". Credential helpers are used
+// where configured, falling back to the credentials stored in the config file.
+// If no credentials are configured, an empty AuthConfig is returned. A helper
+// which is still running when ctx is done is killed.
+func (c *Config) Credentials(ctx context.Context, host string) (registry.AuthConfig, error) {
+	address := ServerAddress(host)
+	helper := c.CredsStore
+	if key, ok := matchRegistry(c.CredHelpers, host, address); ok {
+		helper = c.CredHelpers[key]
+	}
+	if helper != "" {
+		creds, err := helperCredentials(ctx, helper, address)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		if creds.Username != "" || creds.IdentityToken != "" {
+			return creds, nil
+		}
+	}
+	if key, ok := matchRegistry(c.Auths, host, address); ok {
+		return c.Auths[key].authConfig(address)
+	}
+	return registry.AuthConfig{}, nil
+}
+
+// Used to find the key of a registry's entry in the config file. A key which
+// is the registry's address or host is preferred, and otherwise the first key,
+// in sorted order, with the same hostname, such as "https://registry.example.com/v1/",
+// so that the same entry is used each time.
+func matchRegistry[V any](entries map[string]V, host, address string) (string, bool) {
+	for _, key := range []string{address, host} {
+		if _, ok := entries[key]; ok {
+			return key, true
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(entries)) {
+		if hostname(key) == hostname(address) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (e Entry) authConfig(address string) (registry.AuthConfig, error) {
+	creds := registry.AuthConfig{
+		Username:      e.Username,
+		Password:      e.Password,
+		IdentityToken: e.IdentityToken,
+		RegistryToken: e.RegistryToken,
+		ServerAddress: address,
+	}
+	if e.Auth == "" {
+		return creds, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(e.Auth)
+	if err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("invalid credentials for %s: %v", address, err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return registry.AuthConfig{}, fmt.Errorf("invalid credentials for %s: missing password", address)
+	}
+	creds.Username, creds.Password = username, password
+	return creds, nil
+}
+
+// The time to wait for a killed credential helper's output to be closed.
+const helperWaitDelay = time.Second
+
+// Used to get credentials from the docker-credential-<helper> binary, using
+// the same protocol as the Docker CLI. A helper which has no credentials for
+// the registry returns an empty AuthConfig.
+func helperCredentials(ctx context.Context, helper, address string) (registry.AuthConfig, error) {
+	stdout := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	// Processes started by the helper may keep its output open once it has
+	// been killed, so waiting for them is also limited.
+	cmd.WaitDelay = helperWaitDelay
+	cmd.Stdin = strings.NewReader(address)
+	cmd.Stdout = stdout
+	err := cmd.Run()
+	if err != nil {
+		if ctx.Err() != nil {
+			return registry.AuthConfig{}, fmt.Errorf("credential helper %s failed: %w", helper, ctx.Err())
+		}
+		// Helpers report missing credentials on stdout with a failed exit code.
+		if strings.Contains(strings.ToLower(stdout.String()), "credentials not found") {
+			return registry.AuthConfig{}, nil
+		}
+		return registry.AuthConfig{}, fmt.Errorf("credential helper %s failed: %v", helper, err)
+	}
+	var response struct {
+		ServerURL string
+		Username  string
+		Secret    string
+	}
+	err = json.Unmarshal(stdout.Bytes(), &response)
+	if err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("invalid response from credential helper %s: %v", helper, err)
+	}
+	creds := registry.AuthConfig{ServerAddress: address}
+	if response.Username == tokenUsername {
+		creds.IdentityToken = response.Secret
+	} else {
+		creds.Username, creds.Password = response.Username, response.Secret
+	}
+	return creds, nil
+}
+
+// RegistryHost is used to get the registry of an image reference, such as
+// "registry.example.com:5000" for "registry.example.com:5000/app:1.0", or
+// "docker.io" for "postgres:16".
+func RegistryHost(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %v", image, err)
+	}
+	return reference.Domain(named), nil
+}
+
+// ServerAddress is used to get the address which a registry's credentials are
+// stored under. Docker Hub credentials are stored under DockerHubAddress, and
+// those of other registries under their host.
+func ServerAddress(host string) string {
+	if hostname(host) == "docker.io" {
+		return DockerHubAddress
+	}
+	return host
+}
+
+// ForImage is used to get the credentials of the registry which the image is
+// pulled from, using the config file at the specified path, or the default
+// path if empty.
+func ForImage(ctx context.Context, configPath, image string) (registry.AuthConfig, error) {
+	host, err := RegistryHost(image)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	return config.Credentials(ctx, host)
+}
+
+// Encode is used to encode credentials for the X-Registry-Auth header. Empty
+// credentials are encoded as an empty string, so that no header is sent.
+func Encode(creds registry.AuthConfig) (string, error) {
+	if creds == (registry.AuthConfig{}) {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(creds)
+}
+
+// Used to remove the scheme and path of a registry address, so that addresses
+// such as "https://registry.example.com/v1/" match "registry.example.com".
+func hostname(address string) string {
+	address = strings.TrimPrefix(address, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	host, _, _ := strings.Cut(address, "/")
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return host
+}
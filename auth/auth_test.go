@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+// Used to write a fake docker-credential-<name> helper to a directory on the
+// PATH, which responds to "get" with the given output and exit code.
+func writeCredentialHelper(t *testing.T, name, output string, exitCode int) {
+	t.Helper()
+	writeCredentialHelperScript(t, name, "cat > /dev/null\nprintf '%s' '"+output+"'\nexit "+strconv.Itoa(exitCode)+"\n")
+}
+
+// Used to write a fake docker-credential-<name> helper which runs the given
+// shell script.
+func writeCredentialHelperScript(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake credential helpers are shell scripts")
+	}
+	dir := t.TempDir()
+	script = "#!/bin/sh\n" + script
+	err := os.WriteFile(filepath.Join(dir, "docker-credential-"+name), []byte(script), 0o755)
+	assert.Nil(t, err)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig_GivenMissingFile_ReturnsEmptyConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join(t.TempDir(), "config.json"))
+
+	assert.Nil(t, err)
+	creds, err := config.Credentials(context.Background(), "registry.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, registry.AuthConfig{}, creds)
+}
+
+func TestForImage_GivenStoredAuth_ReturnsDecodedCredentials(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("user:secret:with:colons"))
+	path := writeConfig(t, `{"auths": {"https://registry.example.com:5000/v1/": {"auth": "`+encoded+`"}}}`)
+
+	creds, err := ForImage(context.Background(), path, "registry.example.com:5000/team/app:1.0")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "user", creds.Username)
+	assert.Equal(t, "secret:with:colons", creds.Password)
+	assert.Equal(t, "registry.example.com:5000", creds.ServerAddress)
+}
+
+func TestForImage_GivenDockerHubImage_UsesDockerHubAddress(t *testing.T) {
+	path := writeConfig(t, `{"auths": {"https://index.docker.io/v1/": {"username": "hub", "password": "pw"}}}`)
+
+	creds, err := ForImage(context.Background(), path, "postgres:16")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hub", creds.Username)
+	assert.Equal(t, DockerHubAddress, creds.ServerAddress)
+}
+
+func TestCredentials_GivenCredHelper_ReturnsHelperCredentials(t *testing.T) {
+	writeCredentialHelper(t, "fake", `{"ServerURL":"registry.example.com","Username":"helper","Secret":"token"}`, 0)
+	config := &Config{
+		CredsStore:  "missing",
+		CredHelpers: map[string]string{"registry.example.com": "fake"},
+	}
+
+	creds, err := config.Credentials(context.Background(), "registry.example.com")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "helper", creds.Username)
+	assert.Equal(t, "token", creds.Password)
+}
+
+func TestCredentials_GivenHelperIdentityToken_ReturnsIdentityToken(t *testing.T) {
+	writeCredentialHelper(t, "fake", `{"ServerURL":"registry.example.com","Username":"<token>","Secret":"refresh"}`, 0)
+	config := &Config{CredsStore: "fake"}
+
+	creds, err := config.Credentials(context.Background(), "registry.example.com")
+
+	assert.Nil(t, err)
+	assert.Empty(t, creds.Username)
+	assert.Equal(t, "refresh", creds.IdentityToken)
+}
+
+func TestCredentials_GivenHelperWithoutCredentials_FallsBackToAuths(t *testing.T) {
+	writeCredentialHelper(t, "fake", "credentials not found in native keychain", 1)
+	config := &Config{
+		CredsStore: "fake",
+		Auths:      map[string]Entry{"registry.example.com": {Username: "user", Password: "pw"}},
+	}
+
+	creds, err := config.Credentials(context.Background(), "registry.example.com")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "user", creds.Username)
+}
+
+func TestCredentials_GivenAliasedEntries_PrefersExactKeyThenSortedAlias(t *testing.T) {
+	config := &Config{Auths: map[string]Entry{
+		"registry-1.docker.io":        {Username: "registry-1"},
+		"index.docker.io":             {Username: "index"},
+		"docker.io":                   {Username: "host"},
+		"https://index.docker.io/v1/": {Username: "address"},
+	}}
+	for range 20 {
+		creds, err := config.Credentials(context.Background(), "docker.io")
+		assert.Nil(t, err)
+		assert.Equal(t, "address", creds.Username)
+	}
+
+	delete(config.Auths, "https://index.docker.io/v1/")
+	delete(config.Auths, "docker.io")
+	for range 20 {
+		creds, err := config.Credentials(context.Background(), "docker.io")
+		assert.Nil(t, err)
+		assert.Equal(t, "index", creds.Username)
+	}
+}
+
+func TestCredentials_GivenFailingHelper_ReturnsError(t *testing.T) {
+	writeCredentialHelper(t, "fake", "boom", 2)
+	config := &Config{CredsStore: "fake"}
+
+	_, err := config.Credentials(context.Background(), "registry.example.com")
+
+	assert.NotNil(t, err)
+}
+
+func TestCredentials_WhenHelperHangs_ReturnsOnceContextIsDone(t *testing.T) {
+	writeCredentialHelperScript(t, "fake", "sleep 10\n")
+	config := &Config{CredsStore: "fake"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+
+	_, err := config.Credentials(ctx, "registry.example.com")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestEncode_GivenEmptyCredentials_ReturnsEmptyHeader(t *testing.T) {
+	header, err := Encode(registry.AuthConfig{})
+	assert.Nil(t, err)
+	assert.Empty(t, header)
+
+	header, err = Encode(registry.AuthConfig{Username: "user", Password: "pw"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, header)
+}
@@ -2,6 +2,8 @@ package dockerclient
 
 import (
 	"github.com/docker/docker/client"
+
+	"github.com/james226/dockerclient/options"
 )
 
 type DockerClient struct {
@@ -9,19 +11,55 @@ type DockerClient struct {
 	Networks   NetworkOperations
 	Images     ImageOperations
 	Containers ContainerOperations
+	Volumes    VolumeOperations
 }
 
-func NewClient() (*DockerClient, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewClient is used to create a client for the Docker daemon. By default the
+// daemon is configured by the environment, the same as the Docker CLI, which
+// can be overridden with options. An error is returned if the configured
+// registry mirrors or tag rewrites are not valid.
+func NewClient(opts ...*options.ClientOptions) (*DockerClient, error) {
+	opt := options.Client()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	cli, err := client.NewClientWithOpts(opt.DockerOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newDockerClient(cli, opt)
 	if err != nil {
+		cli.Close()
 		return nil, err
 	}
+	return c, nil
+}
+
+// NewClientFromDocker is used to create a client which wraps an existing Docker
+// client. Options which configure the connection to the daemon are ignored, and
+// closing the returned client also closes cli. An error is returned if the
+// configured registry mirrors or tag rewrites are not valid.
+func NewClientFromDocker(cli *client.Client, opts ...*options.ClientOptions) (*DockerClient, error) {
+	opt := options.Client()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	return newDockerClient(cli, opt)
+}
 
+func newDockerClient(cli *client.Client, opt *options.ClientOptions) (*DockerClient, error) {
+	logger := opt.Logger()
+	output := opt.Output()
+	rewrites, err := newImageRewrites(opt.RegistryMirrors(), opt.TagRewrites())
+	if err != nil {
+		return nil, err
+	}
 	return &DockerClient{
 		cli:        cli,
 		Networks:   NetworkOperations{cli},
-		Images:     ImageOperations{cli},
-		Containers: ContainerOperations{cli},
+		Images:     ImageOperations{cli, logger, output, rewrites},
+		Containers: ContainerOperations{cli, logger, output, rewrites},
+		Volumes:    VolumeOperations{cli},
 	}, nil
 }
 
@@ -1,15 +1,30 @@
 package dockerclient
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"github.com/james226/dockerclient/internal"
+	"github.com/james226/dockerclient/logs"
 	"github.com/james226/dockerclient/options"
 )
 
@@ -17,11 +32,16 @@ type Container struct {
 	ID   string
 	Name string
 
-	cli *client.Client
+	cli    *client.Client
+	logger *slog.Logger
+	output io.Writer
 }
 
 type ContainerOperations struct {
-	cli *client.Client
+	cli      *client.Client
+	logger   *slog.Logger
+	output   io.Writer
+	rewrites imageRewrites
 }
 
 func (c ContainerOperations) Start(ctx context.Context, image *Image, net *Network, opts ...*options.StartContainerOptions) (*Container, error) {
@@ -29,23 +49,43 @@ func (c ContainerOperations) Start(ctx context.Context, image *Image, net *Netwo
 	if len(opts) > 0 {
 		opt = opts[0]
 	}
+	images := ImageOperations{cli: c.cli, logger: c.logger, output: c.output, rewrites: c.rewrites}
+	imageRef := image.Name
+	if policy, ok := opt.PullPolicy(); ok {
+		ensured, err := images.Ensure(ctx, image.Name, policy, opt.PullOptions())
+		if err != nil {
+			return nil, err
+		}
+		imageRef = ensured.Name
+	} else if rewritten, err := c.rewrites.rewrite(image.Name); err == nil && rewritten != image.Name {
+		// The image may only exist locally under its rewritten reference.
+		found, err := images.findLocal(ctx, image.Name)
+		if err == nil {
+			imageRef = found.Name
+		}
+	}
 	name, hasName := opt.Name()
 	if hasName {
-		err := removeContainer(ctx, c.cli, name, false)
-		if err != nil && !client.IsErrNotFound(err) {
+		err := c.removeContainer(ctx, name, false)
+		if err != nil && !errors.Is(err, ErrNotFound) {
 			return nil, err
 		}
 	} else {
-		name = image.Name
+		name = defaultContainerName(image)
 	}
 	portSet, portBindings, err := opt.Ports()
 	if err != nil {
 		return nil, err
 	}
+	mounts, err := opt.Mounts()
+	if err != nil {
+		return nil, err
+	}
 	hostConfig := &container.HostConfig{
 		PortBindings: portBindings,
 		AutoRemove:   true,
 		CapAdd:       opt.CapAdd(),
+		Mounts:       mounts,
 	}
 	if net != nil {
 		hostConfig.NetworkMode = container.NetworkMode(net.ID)
@@ -60,59 +100,406 @@ func (c ContainerOperations) Start(ctx context.Context, image *Image, net *Netwo
 		}
 	}
 	resp, err := c.cli.ContainerCreate(ctx, &container.Config{
-		Image:        image.Name,
+		Image:        imageRef,
 		Hostname:     name,
 		ExposedPorts: portSet,
 		Env:          opt.EnvironmentVariables(),
 		Tty:          false,
 	}, hostConfig, nil, dockerPlatform, name)
 	if err != nil {
-		return nil, err
+		return nil, dockerError(err)
+	}
+	containerId := resp.ID
+	cont := &Container{
+		ID:     containerId,
+		Name:   name,
+		cli:    c.cli,
+		logger: c.logger,
+		output: c.output,
 	}
 	err = c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
+	if err != nil {
+		return nil, startFailed(ctx, cont, dockerError(err))
+	}
+	c.logger.Debug("Started container", "container_id", containerId, "container_name", name, "image", imageRef)
+	consumers := opt.LogConsumers()
+	if len(consumers) > 0 {
+		// Following ends once the container stops, so it is detached from the
+		// context, which may only cover starting the container.
+		go func() {
+			_ = cont.Logs(context.WithoutCancel(ctx), options.FollowLogs(consumers...))
+		}()
+	}
+	strategy, hasStrategy := opt.WaitStrategy()
+	if hasStrategy {
+		err = strategy.WaitUntilReady(ctx, waitTarget{cont})
+		if err != nil {
+			return nil, startFailed(ctx, cont, fmt.Errorf("did not become ready: %w", err))
+		}
+	}
+	return cont, nil
+}
+
+// Used to remove a container which failed to start or did not pass its wait
+// strategy, returning a StartError which includes its most recent logs.
+func startFailed(ctx context.Context, c *Container, cause error) error {
+	// The original context may have expired while waiting, so cleanup uses a
+	// context which is detached from its cancellation.
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	logs, err := containerLogs(cleanupCtx, c.cli, c.ID, readinessLogTail)
+	if err != nil {
+		logs = []byte(fmt.Sprintf("<failed to get logs: %v>", err))
+	}
+	// A container which never started is not auto removed, so it is removed
+	// forcefully rather than stopped.
+	err = c.cli.ContainerRemove(cleanupCtx, c.ID, container.RemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	if err != nil && !cerrdefs.IsNotFound(err) && !cerrdefs.IsConflict(err) {
+		c.logger.Warn("Failed to remove container", "container_id", c.ID, "container_name", c.Name, "phase", "start", "error", err)
+	}
+	return &StartError{
+		ContainerID:   c.ID,
+		ContainerName: c.Name,
+		Logs:          string(logs),
+		Err:           cause,
+	}
+}
+
+func (c *Container) Stop(ctx context.Context, logOutput bool) error {
+	return c.stop(ctx, logOutput)
+}
+
+// Logs is used to read the container's output, delivering each line to the
+// consumers registered on the options. When following, the call blocks until
+// the container stops or the context is cancelled.
+func (c *Container) Logs(ctx context.Context, opts ...*options.LogsOptions) error {
+	opt := options.Logs()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	out, err := c.cli.ContainerLogs(ctx, c.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opt.Follow(),
+		Since:      opt.Since(),
+		Until:      opt.Until(),
+		Tail:       opt.Tail(),
+		Timestamps: opt.Timestamps(),
+	})
+	if err != nil {
+		return dockerError(err)
+	}
+	defer out.Close()
+	err = consumeLogs(out, opt.Timestamps(), opt.Consumers())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Used to demultiplex a container's log stream, delivering each line to the
+// consumers. When timestamps is true, each line is expected to be prefixed
+// with the time Docker received it.
+func consumeLogs(r io.Reader, timestamps bool, consumers []logs.Consumer) error {
+	dispatch := func(stream logs.Stream) *internal.LineWriter {
+		return internal.NewLineWriter(func(text string) {
+			line := logs.Line{Stream: stream, Text: text}
+			if timestamps {
+				prefix, rest, ok := strings.Cut(text, " ")
+				t, err := time.Parse(time.RFC3339Nano, prefix)
+				if ok && err == nil {
+					line.Timestamp = t
+					line.Text = rest
+				}
+			}
+			for _, consumer := range consumers {
+				consumer.Accept(line)
+			}
+		})
+	}
+	stdout := dispatch(logs.Stdout)
+	stderr := dispatch(logs.Stderr)
+	_, err := stdcopy.StdCopy(stdout, stderr, r)
+	stdout.Flush()
+	stderr.Flush()
+	return err
+}
+
+// MappedPort is used to look up the host port which the given container port
+// has been published on. This is needed when the port was bound to an ephemeral
+// host port with options.WithRandomPortBinding.
+func (c *Container) MappedPort(ctx context.Context, port uint16, protocol string) (uint16, error) {
+	containerPort, err := nat.NewPort(protocol, strconv.Itoa(int(port)))
+	if err != nil {
+		return 0, err
+	}
+	data, err := c.cli.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return 0, dockerError(err)
+	}
+	if data.NetworkSettings != nil {
+		for _, binding := range data.NetworkSettings.Ports[containerPort] {
+			if binding.HostPort == "" {
+				continue
+			}
+			hostPort, err := strconv.ParseUint(binding.HostPort, 10, 16)
+			if err != nil {
+				return 0, fmt.Errorf("invalid host port '%s' for %s: %v", binding.HostPort, containerPort, err)
+			}
+			return uint16(hostPort), nil
+		}
+	}
+	return 0, fmt.Errorf("port %s of container '%s' is not published", containerPort, c.Name)
+}
+
+// Endpoint is used to get the "host:port" address which the given TCP port of
+// the container can be reached on from the host.
+func (c *Container) Endpoint(ctx context.Context, port uint16) (string, error) {
+	hostPort, err := c.MappedPort(ctx, port, "tcp")
+	if err != nil {
+		return "", err
+	}
+	host, err := daemonHost(c.cli)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(hostPort))), nil
+}
+
+// CopyTo is used to copy a file or directory from the host into the container,
+// where it is written at containerPath. The parent directory of containerPath
+// must already exist. File modes, ownership and symlinks are preserved.
+func (c *Container) CopyTo(ctx context.Context, hostPath, containerPath string) error {
+	_, err := os.Lstat(hostPath)
+	if err != nil {
+		return err
+	}
+	content := streamTar(func(tw *tar.Writer) error {
+		return tarPath(tw, hostPath, path.Base(containerPath))
+	})
+	defer content.Close()
+	err = c.cli.CopyToContainer(ctx, c.ID, path.Dir(containerPath), content, container.CopyToContainerOptions{
+		CopyUIDGID: true,
+	})
+	return dockerError(err)
+}
+
+// CopyFileTo is used to write the contents of r to a file at containerPath
+// inside of the container, with the given file mode. The file is owned by root.
+func (c *Container) CopyFileTo(ctx context.Context, r io.Reader, containerPath string, mode fs.FileMode) error {
+	// The size of a tar entry must be known before its body is written.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read content for %s: %v", containerPath, err)
+	}
+	content := streamTar(func(tw *tar.Writer) error {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Base(containerPath),
+			Mode:     int64(mode.Perm()),
+			Size:     int64(len(data)),
+			ModTime:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	defer content.Close()
+	err = c.cli.CopyToContainer(ctx, c.ID, path.Dir(containerPath), content, container.CopyToContainerOptions{})
+	return dockerError(err)
+}
+
+// CopyFSTo is used to copy the contents of fsys, such as an embed.FS, into a
+// directory at containerPath inside of the container. The files are owned by root.
+func (c *Container) CopyFSTo(ctx context.Context, fsys fs.FS, containerPath string) error {
+	content := streamTar(func(tw *tar.Writer) error {
+		return tarFS(tw, fsys, path.Base(containerPath))
+	})
+	defer content.Close()
+	err := c.cli.CopyToContainer(ctx, c.ID, path.Dir(containerPath), content, container.CopyToContainerOptions{})
+	return dockerError(err)
+}
+
+// CopyFrom is used to copy a file or directory from the container to hostPath.
+// File modes and modification times are preserved, as is ownership when the
+// current user is permitted to change it.
+func (c *Container) CopyFrom(ctx context.Context, containerPath, hostPath string) error {
+	content, _, err := c.cli.CopyFromContainer(ctx, c.ID, containerPath)
+	if err != nil {
+		return dockerError(err)
+	}
+	defer content.Close()
+	return extractTar(content, hostPath)
+}
+
+// CopyFileFrom is used to read the contents of a single file inside of the
+// container. The returned reader must be closed by the caller.
+func (c *Container) CopyFileFrom(ctx context.Context, containerPath string) (io.ReadCloser, error) {
+	content, stat, err := c.cli.CopyFromContainer(ctx, c.ID, containerPath)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	if !stat.Mode.IsRegular() {
+		content.Close()
+		return nil, fmt.Errorf("%s in container '%s' is not a regular file", containerPath, c.Name)
+	}
+	tr := tar.NewReader(content)
+	_, err = tr.Next()
+	if err != nil {
+		content.Close()
+		return nil, fmt.Errorf("failed to read %s from container '%s': %v", containerPath, c.Name, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{tr, content}, nil
+}
+
+// ExecResult holds the outcome of a command executed inside of a container.
+type ExecResult struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// Exec is used to run a command inside of the running container. The call blocks
+// until the command has exited, returning its exit code along with the captured
+// stdout and stderr. A non-zero exit code is not treated as an error.
+func (c *Container) Exec(ctx context.Context, cmd []string, opts ...*options.ExecOptions) (*ExecResult, error) {
+	opt := options.Exec()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	user, _ := opt.User()
+	workingDir, _ := opt.WorkingDir()
+	stdin := opt.Stdin()
+	exec, err := c.cli.ContainerExecCreate(ctx, c.ID, container.ExecOptions{
+		Cmd:          cmd,
+		Env:          opt.EnvironmentVariables(),
+		User:         user,
+		WorkingDir:   workingDir,
+		Tty:          opt.Tty(),
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	attach, err := c.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{Tty: opt.Tty()})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	defer attach.Close()
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(attach.Conn, stdin)
+			_ = attach.CloseWrite()
+		}()
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	stdoutDst := io.Writer(stdout)
+	if w := opt.Stdout(); w != nil {
+		stdoutDst = io.MultiWriter(stdout, w)
+	}
+	stderrDst := io.Writer(stderr)
+	if w := opt.Stderr(); w != nil {
+		stderrDst = io.MultiWriter(stderr, w)
+	}
+	outputDone := make(chan error, 1)
+	go func() {
+		// With a TTY attached the output is a single raw stream, otherwise it
+		// is multiplexed and needs splitting into stdout and stderr.
+		var err error
+		if opt.Tty() {
+			_, err = io.Copy(stdoutDst, attach.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(stdoutDst, stderrDst, attach.Reader)
+		}
+		outputDone <- err
+	}()
+	select {
+	case err := <-outputDone:
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output of exec in container '%s': %v", c.Name, err)
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	exitCode, err := waitForExec(ctx, c.cli, exec.ID)
 	if err != nil {
 		return nil, err
 	}
-	containerId := resp.ID
-	return &Container{
-		ID:   containerId,
-		Name: name,
-		cli:  c.cli,
+	return &ExecResult{
+		ExitCode: exitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
 	}, nil
 }
 
-func (c *Container) Stop(ctx context.Context, logOutput bool) error {
-	return stopContainer(ctx, c.cli, c.ID, c.Name, logOutput)
+// Used to wait for an exec process to be reported as exited, as the output stream
+// can close slightly before the daemon records the exit code.
+func waitForExec(ctx context.Context, cli *client.Client, execID string) (int, error) {
+	for {
+		inspect, err := cli.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, dockerError(err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
 }
 
-func stopContainer(ctx context.Context, cli *client.Client, containerID, containerName string, logOutput bool) error {
-	data, err := cli.ContainerInspect(ctx, containerID)
-	if client.IsErrNotFound(err) || (err == nil && data.State.Status == "removing") {
+func (c *Container) stop(ctx context.Context, logOutput bool) error {
+	data, err := c.cli.ContainerInspect(ctx, c.ID)
+	if cerrdefs.IsNotFound(err) || (err == nil && data.State.Status == "removing") {
 		return nil
 	}
+	if err != nil {
+		return dockerError(err)
+	}
 	// Take logs before the container is stopped as the logs are
-	// lost at that point, due to auto removal.
+	// lost at that point, due to auto removal. Failing to print the logs
+	// does not prevent the container from being stopped.
+	var logErr error
 	if logOutput && data.State.Running {
-		out, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true})
-		if err != nil {
-			fmt.Printf("Failed to get logs for container '%s': %v\n", containerName, err)
+		out, err := c.cli.ContainerLogs(ctx, c.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+		if err == nil {
+			logErr = internal.PrintContainerLogs(c.Name, out, c.output)
+			out.Close()
+		} else {
+			logErr = fmt.Errorf("failed to get logs for container '%s': %w", c.Name, dockerError(err))
 		}
-		internal.PrintContainerLogs(containerID, out)
 	}
-	err = cli.ContainerStop(ctx, containerID, container.StopOptions{})
-	if err != nil {
-		fmt.Printf("Failed to stop container '%s': %v\n", containerName, err)
-		return err
+	err = c.cli.ContainerStop(ctx, c.ID, container.StopOptions{})
+	if err != nil && !cerrdefs.IsNotFound(err) {
+		return errors.Join(logErr, fmt.Errorf("failed to stop container '%s': %w", c.Name, dockerError(err)))
 	}
-	statusCh, errCh := cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	statusCh, errCh := c.cli.ContainerWait(ctx, c.ID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
-		if err != nil {
-			fmt.Printf("An error occured while waiting for container '%s' to stop: %v\n", containerName, err)
+		// The container may already have been auto removed, once stopped.
+		if err != nil && !cerrdefs.IsNotFound(err) {
+			return errors.Join(logErr, fmt.Errorf("failed waiting for container '%s' to stop: %w", c.Name, dockerError(err)))
 		}
 	case <-statusCh:
 	}
-	return nil
+	c.logger.Debug("Stopped container", "container_id", c.ID, "container_name", c.Name, "phase", "stop")
+	return logErr
 }
 
 func getContainerId(ctx context.Context, cli *client.Client, containerName string) (string, error) {
@@ -120,7 +507,7 @@ func getContainerId(ctx context.Context, cli *client.Client, containerName strin
 		All: true,
 	})
 	if err != nil {
-		return "", err
+		return "", dockerError(err)
 	}
 	dockerContainerName := fmt.Sprintf("/%s", containerName)
 	for _, cont := range containers {
@@ -133,23 +520,27 @@ func getContainerId(ctx context.Context, cli *client.Client, containerName strin
 	return "", nil
 }
 
-func removeContainer(ctx context.Context, cli *client.Client, containerName string, logOutput bool) error {
-	containerId, err := getContainerId(ctx, cli, containerName)
+func (c ContainerOperations) removeContainer(ctx context.Context, containerName string, logOutput bool) error {
+	containerId, err := getContainerId(ctx, c.cli, containerName)
 	if err != nil {
 		return err
 	}
 	if containerId == "" {
 		return nil
 	}
-	err = stopContainer(ctx, cli, containerId, containerName, logOutput)
+	existing := &Container{
+		ID:     containerId,
+		Name:   containerName,
+		cli:    c.cli,
+		logger: c.logger,
+		output: c.output,
+	}
+	err = existing.stop(ctx, logOutput)
 	if err != nil {
 		return err
 	}
-	err = cli.ContainerRemove(ctx, containerId, container.RemoveOptions{
+	err = c.cli.ContainerRemove(ctx, containerId, container.RemoveOptions{
 		RemoveVolumes: true,
 	})
-	if err != nil {
-		return err
-	}
-	return nil
+	return dockerError(err)
 }
@@ -0,0 +1,73 @@
+package dockerclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/logs"
+	"github.com/james226/dockerclient/options"
+)
+
+func TestConsumeLogs_GivenMultiplexedStream_DeliversLinesPerStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	stdcopy.NewStdWriter(buf, stdcopy.Stdout).Write([]byte("2024-01-02T03:04:05Z first\n2024-01-02T03:04:06Z sec"))
+	stdcopy.NewStdWriter(buf, stdcopy.Stderr).Write([]byte("2024-01-02T03:04:07Z oops\n"))
+	stdcopy.NewStdWriter(buf, stdcopy.Stdout).Write([]byte("ond\n"))
+	ring := logs.NewRingBuffer(10)
+
+	err := consumeLogs(buf, true, []logs.Consumer{ring})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []logs.Line{
+		{Stream: logs.Stdout, Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Text: "first"},
+		{Stream: logs.Stderr, Timestamp: time.Date(2024, 1, 2, 3, 4, 7, 0, time.UTC), Text: "oops"},
+		{Stream: logs.Stdout, Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC), Text: "second"},
+	}, ring.Lines())
+}
+
+func TestStart_GivenRewritesAndLocalImage_CreatesContainerFromLocalImage(t *testing.T) {
+	tests := map[string]*options.StartContainerOptions{
+		"pull policy":    options.StartContainer().WithPullPolicy(options.PullIfNotPresent),
+		"no pull policy": options.StartContainer(),
+	}
+	for name, opt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var created string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/images/postgres:16/json"):
+					w.Write([]byte(`{"Id":"sha256:9f1c","RepoTags":["postgres:16"]}`))
+				case strings.HasSuffix(r.URL.Path, "/containers/create"):
+					var config container.Config
+					json.NewDecoder(r.Body).Decode(&config)
+					created = config.Image
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"Id":"abc"}`))
+				case strings.HasSuffix(r.URL.Path, "/containers/abc/start"):
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"message":"not found"}`))
+				}
+			}))
+			defer server.Close()
+			c := newTestClientWithOptions(t, server, options.WithRegistryMirror("docker.io", "mirror.example.com/dockerhub"))
+
+			_, err := c.Containers.Start(context.Background(), &Image{Name: "postgres:16"}, nil, opt)
+
+			assert.Nil(t, err)
+			assert.Equal(t, "postgres:16", created)
+		})
+	}
+}
@@ -0,0 +1,342 @@
+// Package dockerfile is used to build Dockerfiles programmatically, and to
+// parse existing Dockerfiles into the same model for inspection.
+package dockerfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instruction is a single instruction of a Dockerfile stage, such as RUN or COPY.
+type Instruction struct {
+	// Command is the upper case name of the instruction, such as "RUN".
+	Command string
+	// Flags are the options which precede the arguments, such as "--from=build".
+	Flags []string
+	// Args are the arguments of the instruction. In shell form, the arguments of
+	// RUN, CMD, ENTRYPOINT and HEALTHCHECK are held as a single command line. For
+	// HEALTHCHECK, Args holds the command following CMD, and is empty for NONE.
+	Args []string
+	// JSON is true when the arguments are in exec form, such as ["echo", "hi"].
+	JSON bool
+}
+
+// String is used to render the instruction as a single Dockerfile line.
+func (in Instruction) String() string {
+	parts := []string{in.Command}
+	parts = append(parts, in.Flags...)
+	if in.Command == "HEALTHCHECK" {
+		if len(in.Args) == 0 {
+			return "HEALTHCHECK NONE"
+		}
+		parts = append(parts, "CMD")
+	}
+	if in.JSON {
+		parts = append(parts, jsonArgs(in.Args))
+	} else {
+		parts = append(parts, in.Args...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Stage is a single stage of a Dockerfile, starting with a FROM instruction.
+type Stage struct {
+	// Image is the base image of the stage, or the name of an earlier stage.
+	Image string
+	// Name is the name given to the stage with AS. Unnamed stages have no name.
+	Name string
+	// Flags are the options of the FROM instruction, such as "--platform=linux/amd64".
+	Flags []string
+	// Instructions are the instructions of the stage, following FROM.
+	Instructions []Instruction
+}
+
+// From is used to render the stage's FROM instruction.
+func (s *Stage) From() string {
+	parts := []string{"FROM"}
+	parts = append(parts, s.Flags...)
+	parts = append(parts, s.Image)
+	if s.Name != "" {
+		parts = append(parts, "AS", s.Name)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Find is used to get the instructions of the stage with the given command,
+// such as "EXPOSE". The command is matched case-insensitively.
+func (s *Stage) Find(command string) []Instruction {
+	found := make([]Instruction, 0)
+	for _, in := range s.Instructions {
+		if strings.EqualFold(in.Command, command) {
+			found = append(found, in)
+		}
+	}
+	return found
+}
+
+// Dockerfile is a Dockerfile made up of one or more stages. The builder methods
+// add instructions to the most recent stage, started with From.
+type Dockerfile struct {
+	// Args are the ARG instructions which precede the first FROM, and can be
+	// used in the FROM instructions.
+	Args []Instruction
+	// Stages are the stages of the Dockerfile, in order.
+	Stages []*Stage
+}
+
+// New returns a new, empty instance of Dockerfile.
+func New() *Dockerfile {
+	return &Dockerfile{}
+}
+
+// From is used to start a new stage using the given base image, which may
+// also be the name of an earlier stage.
+func (d *Dockerfile) From(image string) *Dockerfile {
+	d.Stages = append(d.Stages, &Stage{Image: image})
+	return d
+}
+
+// FromPlatform is used to start a new stage using the given base image, built
+// for the given platform, such as "linux/amd64".
+func (d *Dockerfile) FromPlatform(image, platform string) *Dockerfile {
+	d.Stages = append(d.Stages, &Stage{Image: image, Flags: []string{"--platform=" + platform}})
+	return d
+}
+
+// As is used to name the current stage, so that it can be used as the source
+// of a CopyFrom, as a base image or as the build target.
+func (d *Dockerfile) As(name string) *Dockerfile {
+	if stage := d.current(); stage != nil {
+		stage.Name = name
+	}
+	return d
+}
+
+// Arg is used to declare a build argument. Before the first From, the argument
+// can be used in the FROM instructions. If value is empty, no default is set.
+func (d *Dockerfile) Arg(name, value string) *Dockerfile {
+	arg := name
+	if value != "" {
+		arg += "=" + quote(value)
+	}
+	in := Instruction{Command: "ARG", Args: []string{arg}}
+	if len(d.Stages) == 0 {
+		d.Args = append(d.Args, in)
+		return d
+	}
+	return d.Instruction(in)
+}
+
+// Run is used to run a command, in shell form, while building the image.
+func (d *Dockerfile) Run(command string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "RUN", Args: []string{command}})
+}
+
+// Copy is used to copy files from the build context into the image.
+func (d *Dockerfile) Copy(src, dst string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "COPY", Args: []string{src, dst}})
+}
+
+// CopyFrom is used to copy files from an earlier stage, or another image, into
+// the image.
+func (d *Dockerfile) CopyFrom(stage, src, dst string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "COPY", Flags: []string{"--from=" + stage}, Args: []string{src, dst}})
+}
+
+// Env is used to set an environment variable in the image.
+func (d *Dockerfile) Env(name, value string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "ENV", Args: []string{name + "=" + quote(value)}})
+}
+
+// Label is used to add a label to the image.
+func (d *Dockerfile) Label(name, value string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "LABEL", Args: []string{quote(name) + "=" + quote(value)}})
+}
+
+// Workdir is used to set the working directory of the following instructions
+// and of the container.
+func (d *Dockerfile) Workdir(dir string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "WORKDIR", Args: []string{dir}})
+}
+
+// User is used to set the user, in the format of "user[:group]", which the
+// following instructions and the container are run as.
+func (d *Dockerfile) User(user string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "USER", Args: []string{user}})
+}
+
+// Expose is used to document the ports the container listens on, such as
+// "8080" or "53/udp".
+func (d *Dockerfile) Expose(ports ...string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "EXPOSE", Args: ports})
+}
+
+// Entrypoint is used to set the command, in exec form, which the container runs.
+func (d *Dockerfile) Entrypoint(command ...string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "ENTRYPOINT", Args: command, JSON: true})
+}
+
+// Cmd is used to set the default command, in exec form, of the container. When
+// an entrypoint is set, these are the default arguments of the entrypoint.
+func (d *Dockerfile) Cmd(command ...string) *Dockerfile {
+	return d.Instruction(Instruction{Command: "CMD", Args: command, JSON: true})
+}
+
+// HealthcheckOptions is used to configure how often a health check is run, and
+// how many failures mark the container as unhealthy. Zero values use the
+// daemon's defaults.
+type HealthcheckOptions struct {
+	Interval    time.Duration
+	Timeout     time.Duration
+	StartPeriod time.Duration
+	Retries     int
+}
+
+// Healthcheck is used to set the command, in exec form, which checks whether
+// the container is healthy.
+func (d *Dockerfile) Healthcheck(command []string, opts ...HealthcheckOptions) *Dockerfile {
+	flags := make([]string, 0)
+	if len(opts) > 0 {
+		opt := opts[0]
+		if opt.Interval > 0 {
+			flags = append(flags, "--interval="+opt.Interval.String())
+		}
+		if opt.Timeout > 0 {
+			flags = append(flags, "--timeout="+opt.Timeout.String())
+		}
+		if opt.StartPeriod > 0 {
+			flags = append(flags, "--start-period="+opt.StartPeriod.String())
+		}
+		if opt.Retries > 0 {
+			flags = append(flags, "--retries="+strconv.Itoa(opt.Retries))
+		}
+	}
+	return d.Instruction(Instruction{Command: "HEALTHCHECK", Flags: flags, Args: command, JSON: true})
+}
+
+// Instruction is used to add any instruction to the current stage, such as
+// those which have no builder method.
+func (d *Dockerfile) Instruction(in Instruction) *Dockerfile {
+	stage := d.current()
+	if stage == nil {
+		// Instructions other than ARG are invalid before the first FROM, so
+		// they are kept in a stage without a base image for Validate to report.
+		stage = &Stage{}
+		d.Stages = append(d.Stages, stage)
+	}
+	stage.Instructions = append(stage.Instructions, in)
+	return d
+}
+
+// Stage is used to get the stage with the given name. If no stage has the
+// name, nil is returned.
+func (d *Dockerfile) Stage(name string) *Stage {
+	for _, stage := range d.Stages {
+		if stage.Name != "" && strings.EqualFold(stage.Name, name) {
+			return stage
+		}
+	}
+	return nil
+}
+
+// Validate is used to check the Dockerfile has at least one stage, that each
+// stage has a base image, and that no instruction contains a line break, such
+// as in the value of an ENV, LABEL or ARG, which would be read as the start of
+// another instruction.
+func (d *Dockerfile) Validate() error {
+	if len(d.Stages) == 0 {
+		return fmt.Errorf("dockerfile has no stages")
+	}
+	for _, in := range d.Args {
+		err := checkLineBreaks(in)
+		if err != nil {
+			return err
+		}
+	}
+	for i, stage := range d.Stages {
+		if stage.Image == "" && i == 0 && len(stage.Instructions) > 0 {
+			return fmt.Errorf("%s instruction before the first FROM", stage.Instructions[0].Command)
+		}
+		if stage.Image == "" {
+			return fmt.Errorf("stage %d has no base image", i)
+		}
+		if strings.ContainsAny(stage.Image+stage.Name+strings.Join(stage.Flags, ""), "\r\n") {
+			return fmt.Errorf("stage %d contains a line break", i)
+		}
+		for _, in := range stage.Instructions {
+			err := checkLineBreaks(in)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Used to check an instruction has no line breaks. Arguments in the JSON form
+// are allowed them, as they are escaped when rendered.
+func checkLineBreaks(in Instruction) error {
+	values := in.Flags
+	if !in.JSON {
+		values = append(slices.Clone(values), in.Args...)
+	}
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%s instruction contains a line break: %q", in.Command, value)
+		}
+	}
+	return nil
+}
+
+// String is used to render the Dockerfile. It is not validated, so use Validate
+// first, or build with the WithDockerfileBuilder option, which validates it.
+func (d *Dockerfile) String() string {
+	sb := strings.Builder{}
+	for _, in := range d.Args {
+		sb.WriteString(in.String())
+		sb.WriteString("\n")
+	}
+	for i, stage := range d.Stages {
+		if i > 0 || len(d.Args) > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(stage.From())
+		sb.WriteString("\n")
+		for _, in := range stage.Instructions {
+			sb.WriteString(in.String())
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func (d *Dockerfile) current() *Stage {
+	if len(d.Stages) == 0 {
+		return nil
+	}
+	return d.Stages[len(d.Stages)-1]
+}
+
+func jsonArgs(args []string) string {
+	if args == nil {
+		args = []string{}
+	}
+	b, _ := json.Marshal(args)
+	return string(b)
+}
+
+// Used to quote a value for ENV, LABEL and ARG, when it contains characters
+// which would otherwise split or end it. Line breaks cannot be quoted, so they
+// are reported by Validate.
+func quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\"'\\=") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,142 @@
+package dockerfile
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerfileString_GivenMultiStageBuild_RendersDockerfile(t *testing.T) {
+	d := New().
+		Arg("GO_VERSION", "1.24").
+		From("golang:${GO_VERSION}").As("build").
+		Workdir("/src").
+		Copy(".", ".").
+		Run("go build -o /out/app .").
+		From("alpine:3.20").
+		Env("GREETING", "hello world").
+		CopyFrom("build", "/out/app", "/usr/local/bin/app").
+		Expose("8080", "53/udp").
+		Healthcheck([]string{"app", "health"}, HealthcheckOptions{Interval: 5 * time.Second, Retries: 3}).
+		Entrypoint("app", "serve")
+
+	expected := `ARG GO_VERSION=1.24
+
+FROM golang:${GO_VERSION} AS build
+WORKDIR /src
+COPY . .
+RUN go build -o /out/app .
+
+FROM alpine:3.20
+ENV GREETING="hello world"
+COPY --from=build /out/app /usr/local/bin/app
+EXPOSE 8080 53/udp
+HEALTHCHECK --interval=5s --retries=3 CMD ["app","health"]
+ENTRYPOINT ["app","serve"]
+`
+	assert.Equal(t, expected, d.String())
+	assert.Nil(t, d.Validate())
+}
+
+func TestDockerfileValidate_GivenInstructionBeforeFrom_ReturnsError(t *testing.T) {
+	d := New().Run("echo hi")
+	assert.NotNil(t, d.Validate())
+	assert.NotNil(t, New().Validate())
+}
+
+func TestParse_GivenRenderedDockerfile_ReturnsSameModel(t *testing.T) {
+	d := New().
+		FromPlatform("golang:1.24", "linux/amd64").As("build").
+		Label("org.opencontainers.image.title", "my app").
+		Run("go build ./...").
+		From("scratch").
+		CopyFrom("build", "/app", "/app").
+		Healthcheck([]string{"/app", "health"}).
+		Cmd("/app")
+
+	parsed, err := Parse(strings.NewReader(d.String()))
+
+	assert.Nil(t, err)
+	assert.Equal(t, d.String(), parsed.String())
+	assert.Equal(t, "golang:1.24", parsed.Stage("build").Image)
+	assert.Equal(t, []string{"--platform=linux/amd64"}, parsed.Stage("build").Flags)
+}
+
+func TestParse_GivenDockerfile_ParsesInstructions(t *testing.T) {
+	content := `# syntax=docker/dockerfile:1
+FROM node:20 as deps
+# Install the dependencies
+run npm ci \
+    --omit=dev
+ENV NODE_ENV=production PATH="/app/bin:$PATH"
+EXPOSE 3000
+HEALTHCHECK NONE
+CMD ["node", "server.js"]
+`
+
+	d, err := Parse(strings.NewReader(content))
+
+	assert.Nil(t, err)
+	assert.Len(t, d.Stages, 1)
+	stage := d.Stages[0]
+	assert.Equal(t, "node:20", stage.Image)
+	assert.Equal(t, "deps", stage.Name)
+	assert.Equal(t, []Instruction{
+		{Command: "RUN", Flags: []string{}, Args: []string{"npm ci     --omit=dev"}},
+		{Command: "ENV", Args: []string{"NODE_ENV=production", `PATH="/app/bin:$PATH"`}},
+		{Command: "EXPOSE", Args: []string{"3000"}},
+		{Command: "HEALTHCHECK", Flags: []string{}},
+		{Command: "CMD", Args: []string{"node", "server.js"}, JSON: true},
+	}, stage.Instructions)
+	assert.Len(t, stage.Find("expose"), 1)
+}
+
+func TestParse_GivenEscapeDirective_JoinsContinuedLines(t *testing.T) {
+	content := "# escape=`\nFROM mcr.microsoft.com/windows/servercore\nRUN dir `\n    C:\\\n"
+
+	d, err := Parse(strings.NewReader(content))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dir     C:\\"}, d.Stages[0].Instructions[0].Args)
+}
+
+func TestParse_GivenInvalidDockerfile_ReturnsError(t *testing.T) {
+	tests := map[string]string{
+		"instruction before from": "RUN echo hi\n",
+		"invalid from":            "FROM alpine AS\n",
+		"heredoc":                 "FROM alpine\nRUN <<EOF\necho hi\nEOF\n",
+		"invalid healthcheck":     "FROM alpine\nHEALTHCHECK curl localhost\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(content))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDockerfileValidate_GivenValueWithLineBreak_ReturnsError(t *testing.T) {
+	tests := map[string]*Dockerfile{
+		"global arg": New().Arg("VERSION", "1\nRUN rm -rf /").From("alpine"),
+		"stage arg":  New().From("alpine").Arg("VERSION", "1\n"),
+		"env":        New().From("alpine").Env("GREETING", "hello\nworld"),
+		"label":      New().From("alpine").Label("description", "line one\r\nline two"),
+		"run":        New().From("alpine").Run("echo one\necho two"),
+	}
+	for name, d := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorContains(t, d.Validate(), "line break")
+		})
+	}
+}
+
+func TestDockerfileValidate_GivenJSONArgumentWithLineBreak_RendersEscapedArgument(t *testing.T) {
+	d := New().From("alpine").Cmd("sh", "-c", "echo one\necho two")
+
+	assert.Nil(t, d.Validate())
+	parsed, err := Parse(strings.NewReader(d.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, d.String(), parsed.String())
+}
@@ -0,0 +1,229 @@
+package dockerfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Commands whose arguments are a single command line when in shell form.
+var shellCommands = map[string]bool{
+	"RUN":        true,
+	"CMD":        true,
+	"ENTRYPOINT": true,
+	"ONBUILD":    true,
+}
+
+// Commands which accept flags, such as "--from=build", before their arguments.
+var flagCommands = map[string]bool{
+	"FROM":        true,
+	"RUN":         true,
+	"COPY":        true,
+	"ADD":         true,
+	"HEALTHCHECK": true,
+}
+
+var (
+	directivePattern = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.+?)\s*$`)
+	heredocPattern   = regexp.MustCompile(`(^|\s)<<-?["']?[a-zA-Z_]`)
+)
+
+// Parse is used to read an existing Dockerfile into the same model as the
+// builder, so that it can be inspected or modified. Comments and parser
+// directives are not kept, and heredocs are not supported.
+func Parse(r io.Reader) (*Dockerfile, error) {
+	lines, err := logicalLines(r)
+	if err != nil {
+		return nil, err
+	}
+	d := New()
+	for _, line := range lines {
+		command, rest := cutSpace(line.text)
+		command = strings.ToUpper(command)
+		in := Instruction{Command: command}
+		if flagCommands[command] {
+			in.Flags, rest = splitFlags(rest)
+		}
+		switch {
+		case command == "FROM":
+			stage, err := parseFrom(in.Flags, rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.number, err)
+			}
+			d.Stages = append(d.Stages, stage)
+			continue
+		case command == "ARG" && len(d.Stages) == 0:
+			in.Args = splitWords(rest, line.escape)
+			d.Args = append(d.Args, in)
+			continue
+		case len(d.Stages) == 0:
+			return nil, fmt.Errorf("line %d: %s instruction before FROM", line.number, command)
+		}
+		if (command == "RUN" || command == "COPY" || command == "ADD") && heredocPattern.MatchString(rest) {
+			return nil, fmt.Errorf("line %d: heredocs are not supported", line.number)
+		}
+		if command == "HEALTHCHECK" {
+			var keyword string
+			keyword, rest = cutSpace(rest)
+			switch strings.ToUpper(keyword) {
+			case "NONE":
+				d.Instruction(in)
+				continue
+			case "CMD":
+			default:
+				return nil, fmt.Errorf("line %d: HEALTHCHECK must be followed by CMD or NONE", line.number)
+			}
+		}
+		if args, ok := parseJSONArgs(rest); ok {
+			in.Args, in.JSON = args, true
+		} else if shellCommands[command] || command == "HEALTHCHECK" {
+			in.Args = []string{rest}
+		} else {
+			in.Args = splitWords(rest, line.escape)
+		}
+		d.Instruction(in)
+	}
+	return d, nil
+}
+
+type logicalLine struct {
+	number int
+	text   string
+	escape rune
+}
+
+// Used to read the instructions of a Dockerfile, joining lines which are
+// continued with the escape character and removing comments.
+func logicalLines(r io.Reader) ([]logicalLine, error) {
+	escape := '\\'
+	directives := true
+	lines := make([]logicalLine, 0)
+	current := strings.Builder{}
+	start := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		// Parser directives are only recognised before any other line.
+		if directives {
+			if m := directivePattern.FindStringSubmatch(trimmed); m != nil {
+				if strings.EqualFold(m[1], "escape") {
+					if m[2] != "\\" && m[2] != "`" {
+						return nil, fmt.Errorf("line %d: invalid escape character %q", number, m[2])
+					}
+					escape = rune(m[2][0])
+				}
+				continue
+			}
+			directives = false
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if current.Len() == 0 {
+			start = number
+		}
+		if strings.HasSuffix(text, string(escape)) {
+			current.WriteString(strings.TrimSuffix(text, string(escape)))
+			continue
+		}
+		current.WriteString(text)
+		lines = append(lines, logicalLine{number: start, text: strings.TrimSpace(current.String()), escape: escape})
+		current.Reset()
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dockerfile: %v", err)
+	}
+	if current.Len() > 0 {
+		lines = append(lines, logicalLine{number: start, text: strings.TrimSpace(current.String()), escape: escape})
+	}
+	return lines, nil
+}
+
+func parseFrom(flags []string, rest string) (*Stage, error) {
+	words := strings.Fields(rest)
+	switch {
+	case len(words) == 1:
+		return &Stage{Image: words[0], Flags: flags}, nil
+	case len(words) == 3 && strings.EqualFold(words[1], "AS"):
+		return &Stage{Image: words[0], Name: words[2], Flags: flags}, nil
+	default:
+		return nil, fmt.Errorf("invalid FROM instruction %q", rest)
+	}
+}
+
+// Used to separate the leading flags, such as "--from=build", from the rest
+// of the arguments.
+func splitFlags(rest string) ([]string, string) {
+	flags := make([]string, 0)
+	for strings.HasPrefix(rest, "--") {
+		var flag string
+		flag, rest = cutSpace(rest)
+		flags = append(flags, flag)
+	}
+	return flags, rest
+}
+
+// Used to split s around the first run of whitespace.
+func cutSpace(s string) (string, string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+func parseJSONArgs(rest string) ([]string, bool) {
+	if !strings.HasPrefix(rest, "[") {
+		return nil, false
+	}
+	var args []string
+	err := json.Unmarshal([]byte(rest), &args)
+	if err != nil {
+		return nil, false
+	}
+	return args, true
+}
+
+// Used to split arguments on whitespace, except where it is quoted or escaped.
+// The quotes and escape characters are kept, so that the arguments are
+// rendered unchanged.
+func splitWords(rest string, escape rune) []string {
+	words := make([]string, 0)
+	word := strings.Builder{}
+	var quote rune
+	escaped := false
+	for _, c := range rest {
+		switch {
+		case escaped:
+			escaped = false
+		case c == escape:
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case unicode.IsSpace(c):
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(c)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
@@ -0,0 +1,73 @@
+package dockerclient
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// Used to create a matcher for the files excluded from a build context, from
+// the context's ignore file followed by the extra patterns. A nil matcher is
+// returned when nothing is excluded.
+func buildContextMatcher(buildContext fs.FS, dockerfile string, extra []string) (*patternmatcher.PatternMatcher, error) {
+	dockerfile = path.Clean(filepath.ToSlash(dockerfile))
+	patterns, err := readDockerignore(buildContext, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, extra...)
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid build context exclusion: %v", err)
+	}
+	// The same as the Docker CLI, the Dockerfile and ignore files are always
+	// sent to the daemon, even when excluded.
+	keep := make([]string, 0, 2)
+	for _, name := range []string{dockerfile, ".dockerignore"} {
+		excluded, err := pm.MatchesOrParentMatches(name)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			keep = append(keep, "!"+name)
+		}
+	}
+	if len(keep) == 0 {
+		return pm, nil
+	}
+	return patternmatcher.New(append(patterns, keep...))
+}
+
+// Used to read the patterns of the build context's ignore file. A Dockerfile
+// specific ignore file, such as "app/Dockerfile.dockerignore", takes precedence
+// over the .dockerignore file at the root of the context.
+func readDockerignore(buildContext fs.FS, dockerfile string) ([]string, error) {
+	for _, candidate := range []string{dockerfile + ".dockerignore", ".dockerignore"} {
+		// A Dockerfile outside of the context does not produce a valid path.
+		if !fs.ValidPath(candidate) {
+			continue
+		}
+		f, err := buildContext.Open(candidate)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", candidate, err)
+		}
+		defer f.Close()
+		patterns, err := ignorefile.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", candidate, err)
+		}
+		return patterns, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,91 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+		assert.Nil(t, os.WriteFile(filename, []byte(content), 0o644))
+	}
+}
+
+func buildContextNames(t *testing.T, dir, dockerfile string, extra ...string) []string {
+	excludes, err := buildContextMatcher(os.DirFS(dir), dockerfile, extra)
+	assert.Nil(t, err)
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	assert.Nil(t, loadBuildContext(dir, tw, excludes, nil))
+	assert.Nil(t, tw.Close())
+	names := make([]string, 0)
+	tr := tar.NewReader(buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		if header.Typeflag != tar.TypeDir {
+			names = append(names, header.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestLoadBuildContext_GivenDockerignore_ExcludesMatchingFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore":             "# comment\n.git\nnode_modules\n**/*.log\n!keep.log\nDockerfile\n",
+		"Dockerfile":                "FROM alpine",
+		"main.go":                   "package main",
+		".git/HEAD":                 "ref",
+		"node_modules/pkg/index.js": "",
+		"logs/debug.log":            "",
+		"keep.log":                  "",
+	})
+
+	names := buildContextNames(t, dir, "Dockerfile")
+
+	assert.Equal(t, []string{".dockerignore", "Dockerfile", "keep.log", "main.go"}, names)
+}
+
+func TestLoadBuildContext_GivenDockerfileSpecificIgnore_UsesItInstead(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore":               "main.go\n",
+		"app/Dockerfile":              "FROM alpine",
+		"app/Dockerfile.dockerignore": "docs\n",
+		"main.go":                     "package main",
+		"docs/README.md":              "",
+	})
+
+	names := buildContextNames(t, dir, "app/Dockerfile")
+
+	assert.Equal(t, []string{".dockerignore", "app/Dockerfile", "app/Dockerfile.dockerignore", "main.go"}, names)
+}
+
+func TestLoadBuildContext_GivenExtraExcludes_AppliesThemAfterDockerignore(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".dockerignore": "*.txt\n",
+		"Dockerfile":    "FROM alpine",
+		"a.txt":         "",
+		"b.txt":         "",
+		"coverage.out":  "",
+	})
+
+	names := buildContextNames(t, dir, "Dockerfile", "*.out", "!b.txt")
+
+	assert.Equal(t, []string{".dockerignore", "Dockerfile", "b.txt"}, names)
+}
@@ -0,0 +1,101 @@
+package dockerclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+)
+
+var (
+	// ErrNotFound is matched, using errors.Is, by errors returned when a
+	// container, image, network or volume does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is matched, using errors.Is, by errors returned when an
+	// operation conflicts with the current state of a resource, such as a
+	// container name which is already in use.
+	ErrConflict = errors.New("conflict")
+)
+
+// BuildError is returned when the Docker daemon fails to build an image.
+type BuildError struct {
+	Image string
+	// Step is the build step which failed, such as "Step 3/7 : RUN make".
+	Step    string
+	Message string
+	// LogTail holds the last lines of the build output before it failed.
+	LogTail []string
+}
+
+func (e *BuildError) Error() string {
+	if e.Step == "" {
+		return fmt.Sprintf("failed to build image '%s': %s", e.Image, e.Message)
+	}
+	return fmt.Sprintf("failed to build image '%s' at '%s': %s", e.Image, e.Step, e.Message)
+}
+
+// PullError is returned when an image cannot be pulled from its registry.
+type PullError struct {
+	Image string
+	Err   error
+}
+
+func (e *PullError) Error() string {
+	return fmt.Sprintf("failed to pull image '%s': %v", e.Image, e.Err)
+}
+
+func (e *PullError) Unwrap() error {
+	return e.Err
+}
+
+// PushError is returned when an image cannot be pushed to its registry.
+type PushError struct {
+	Image string
+	Err   error
+}
+
+func (e *PushError) Error() string {
+	return fmt.Sprintf("failed to push image '%s': %v", e.Image, e.Err)
+}
+
+func (e *PushError) Unwrap() error {
+	return e.Err
+}
+
+// StartError is returned when a container was created but failed to start, or
+// did not become ready. It holds the most recent logs of the container.
+type StartError struct {
+	ContainerID   string
+	ContainerName string
+	Logs          string
+	Err           error
+}
+
+func (e *StartError) Error() string {
+	msg := fmt.Sprintf("container '%s' failed to start: %v", e.ContainerName, e.Err)
+	if strings.TrimSpace(e.Logs) == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s\nrecent logs:\n%s", msg, e.Logs)
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// Used to wrap errors returned by the Docker daemon, so they can be matched
+// against ErrNotFound and ErrConflict while keeping the original error.
+func dockerError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case cerrdefs.IsNotFound(err):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case cerrdefs.IsConflict(err):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	default:
+		return err
+	}
+}
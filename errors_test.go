@@ -0,0 +1,51 @@
+package dockerclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerError_GivenNotFoundError_MatchesErrNotFound(t *testing.T) {
+	original := fmt.Errorf("no such container: %w", cerrdefs.ErrNotFound)
+
+	err := dockerError(original)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, original)
+	assert.NotErrorIs(t, err, ErrConflict)
+}
+
+func TestDockerError_GivenConflictError_MatchesErrConflict(t *testing.T) {
+	err := dockerError(fmt.Errorf("name in use: %w", cerrdefs.ErrConflict))
+
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestDockerError_GivenNil_ReturnsNil(t *testing.T) {
+	assert.Nil(t, dockerError(nil))
+}
+
+func TestReadBuildOutput_GivenErrorMessage_ReturnsBuildError(t *testing.T) {
+	output := strings.Join([]string{
+		`{"stream":"Step 1/2 : FROM alpine"}`,
+		`{"stream":"Step 2/2 : RUN exit 1"}`,
+		`{"stream":"\n"}`,
+		`{"errorDetail":{"message":"The command '/bin/sh -c exit 1' returned a non-zero code: 1"},"error":"The command '/bin/sh -c exit 1' returned a non-zero code: 1"}`,
+	}, "\n")
+	ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: io.Discard}
+
+	_, err := ops.readBuildOutput(strings.NewReader(output), "app", nil)
+
+	var buildErr *BuildError
+	assert.True(t, errors.As(err, &buildErr))
+	assert.Equal(t, "Step 2/2 : RUN exit 1", buildErr.Step)
+	assert.Equal(t, []string{"Step 1/2 : FROM alpine", "Step 2/2 : RUN exit 1"}, buildErr.LogTail)
+	assert.Contains(t, err.Error(), "returned a non-zero code: 1")
+}
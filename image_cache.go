@@ -0,0 +1,151 @@
+package dockerclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The name of the file which maps image names to the digests of their tarballs.
+const imageCacheIndex = "index.json"
+
+// ImageCache is used to store saved images as tarballs on disk, keyed by the
+// digest of the image, so that they can be loaded on machines without access
+// to a registry. The cache directory can be copied between machines, such as
+// a CI artifact. An ImageCache is not safe for use by concurrent processes.
+type ImageCache struct {
+	dir    string
+	images ImageOperations
+}
+
+// NewImageCache returns an ImageCache which stores tarballs in dir, using
+// images to save and load them.
+func NewImageCache(images ImageOperations, dir string) *ImageCache {
+	return &ImageCache{dir: dir, images: images}
+}
+
+// Path is used to get the path of the tarball of the image with the given
+// digest, such as "sha256:4b82...".
+func (c *ImageCache) Path(digest string) string {
+	return filepath.Join(c.dir, strings.ReplaceAll(digest, ":", "-")+".tar")
+}
+
+// Save is used to save the image with the given name to the cache. When the
+// cache already has a tarball of the image, it is not saved again. The path of
+// the tarball is returned.
+func (c *ImageCache) Save(ctx context.Context, ref string) (string, error) {
+	img, err := c.images.Get(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	path := c.Path(img.ID)
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = c.save(ctx, ref, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	index, err := c.index()
+	if err != nil {
+		return "", err
+	}
+	index[ref] = img.ID
+	err = c.writeIndex(index)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Load is used to load the cached image with the given name. When the daemon
+// already has the cached version of the image, it is not loaded again. An error
+// matching ErrNotFound is returned if the image is not in the cache.
+func (c *ImageCache) Load(ctx context.Context, ref string) (*Image, error) {
+	index, err := c.index()
+	if err != nil {
+		return nil, err
+	}
+	digest, ok := index[ref]
+	if !ok {
+		return nil, fmt.Errorf("image '%s' is not cached: %w", ref, ErrNotFound)
+	}
+	img, err := c.images.Get(ctx, ref)
+	if err == nil && img.ID == digest {
+		return img, nil
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	f, err := os.Open(c.Path(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("image '%s' is not cached: %w", ref, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cached image: %v", err)
+	}
+	defer f.Close()
+	_, err = c.images.Load(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return c.images.Get(ctx, ref)
+}
+
+// Used to save the image to a temporary file, which is renamed once complete so
+// that an interrupted save does not leave a partial tarball in the cache.
+func (c *ImageCache) save(ctx context.Context, ref, path string) error {
+	err := os.MkdirAll(c.dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create image cache: %v", err)
+	}
+	f, err := os.CreateTemp(c.dir, ".save-*")
+	if err != nil {
+		return fmt.Errorf("failed to create image cache file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	err = c.images.Save(ctx, f, ref)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write image cache file: %v", err)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Used to read the index of cached image names. A missing index is empty.
+func (c *ImageCache) index() (map[string]string, error) {
+	index := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(c.dir, imageCacheIndex))
+	if errors.Is(err, fs.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image cache index: %v", err)
+	}
+	err = json.Unmarshal(data, &index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image cache index: %v", err)
+	}
+	return index, nil
+}
+
+func (c *ImageCache) writeIndex(index map[string]string) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(c.dir, imageCacheIndex), data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write image cache index: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package dockerclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Used to fake a daemon which has a single image, app:1.0, until it is removed
+// by setting present to false.
+type fakeImageDaemon struct {
+	present bool
+	saves   int
+	loaded  string
+}
+
+func (d *fakeImageDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/images/get"):
+		d.saves++
+		w.Write([]byte("image tarball"))
+	case strings.HasSuffix(r.URL.Path, "/images/load"):
+		body, _ := io.ReadAll(r.Body)
+		d.loaded = string(body)
+		d.present = true
+		w.Write([]byte(`{"stream":"Loaded image: app:1.0\n"}`))
+	case strings.HasSuffix(r.URL.Path, "/json") && d.present:
+		w.Write([]byte(`{"Id":"sha256:5b2c","RepoTags":["app:1.0"]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such image: app:1.0"}`))
+	}
+}
+
+func TestImageCache_GivenSavedImage_LoadsImageFromDisk(t *testing.T) {
+	daemon := &fakeImageDaemon{present: true}
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+	c := newTestClient(t, server)
+	cache := NewImageCache(c.Images, t.TempDir())
+
+	path, err := cache.Save(context.Background(), "app:1.0")
+	assert.Nil(t, err)
+	_, err = cache.Save(context.Background(), "app:1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, daemon.saves)
+	assert.Equal(t, cache.Path("sha256:5b2c"), path)
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "image tarball", string(content))
+
+	daemon.present = false
+	img, err := cache.Load(context.Background(), "app:1.0")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "image tarball", daemon.loaded)
+	assert.Equal(t, "sha256:5b2c", img.ID)
+}
+
+func TestImageCacheLoad_GivenUncachedImage_ReturnsNotFound(t *testing.T) {
+	server := httptest.NewServer(&fakeImageDaemon{})
+	defer server.Close()
+	c := newTestClient(t, server)
+	cache := NewImageCache(c.Images, t.TempDir())
+
+	_, err := cache.Load(context.Background(), "app:1.0")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReadLoadOutput_GivenLoadStream_ReturnsLoadedImages(t *testing.T) {
+	output := `{"stream":"Loaded image: app:1.0\n"}
+{"stream":"Loaded image ID: sha256:5b2c\n"}`
+
+	loaded, err := readLoadOutput(strings.NewReader(output))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app:1.0", "sha256:5b2c"}, loaded)
+
+	_, err = readLoadOutput(strings.NewReader(`{"errorDetail":{"message":"invalid tar header"},"error":"invalid tar header"}`))
+	assert.ErrorContains(t, err, "invalid tar header")
+}
@@ -0,0 +1,454 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// The prefix of a file which removes the file of the same name from the
+	// lower layers.
+	whiteoutPrefix = ".wh."
+	// A file which removes every file in its directory from the lower layers.
+	whiteoutOpaque = ".wh..wh..opq"
+	// The number of symlinks followed before a path is considered a loop.
+	maxSymlinks = 40
+)
+
+// ImageFS is the filesystem of an image, reconstructed from its layers without
+// starting a container. It implements fs.FS and fs.StatFS, following symlinks
+// within the image, and reports the mode, size, ownership and modification time
+// of each file. The tar header of each file is available from the Sys method of
+// its fs.FileInfo. Layers are stored in temporary files, which are removed by Close.
+type ImageFS struct {
+	root  *imageFile
+	files []*os.File
+}
+
+// A file or directory of an image, with the layer which holds its content.
+type imageFile struct {
+	header   *tar.Header
+	data     io.ReaderAt
+	offset   int64
+	children map[string]*imageFile
+}
+
+// Filesystem is used to export the image with the given name or ID, and apply
+// its layers in order, including their whiteouts, to reconstruct the image's
+// filesystem. The returned ImageFS must be closed once it is no longer used.
+func (i ImageOperations) Filesystem(ctx context.Context, ref string) (*ImageFS, error) {
+	archive, err := os.CreateTemp("", "dockerclient-image-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image archive: %v", err)
+	}
+	fsys := &ImageFS{
+		root:  newImageDir("."),
+		files: []*os.File{archive},
+	}
+	err = i.Save(ctx, archive, ref)
+	if err == nil {
+		err = fsys.load(archive)
+	}
+	if err != nil {
+		fsys.Close()
+		return nil, err
+	}
+	return fsys, nil
+}
+
+// Close is used to remove the temporary files holding the image's layers.
+func (f *ImageFS) Close() error {
+	errs := make([]error, 0)
+	for _, file := range f.files {
+		errs = append(errs, file.Close(), os.Remove(file.Name()))
+	}
+	f.files = nil
+	return errors.Join(errs...)
+}
+
+// Open is used to open the named file, following symlinks.
+func (f *ImageFS) Open(name string) (fs.File, error) {
+	node, err := f.lookup("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if node.header.Typeflag == tar.TypeDir {
+		return &imageDir{node: node}, nil
+	}
+	size := node.header.Size
+	if node.data == nil {
+		size = 0
+	}
+	return &imageFileReader{node: node, SectionReader: io.NewSectionReader(node.data, node.offset, size)}, nil
+}
+
+// Stat is used to describe the named file, following symlinks.
+func (f *ImageFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := f.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return node.header.FileInfo(), nil
+}
+
+// Lstat is used to describe the named file, without following a final symlink.
+func (f *ImageFS) Lstat(name string) (fs.FileInfo, error) {
+	node, err := f.lookup("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return node.header.FileInfo(), nil
+}
+
+// ReadLink is used to get the target of the named symlink.
+func (f *ImageFS) ReadLink(name string) (string, error) {
+	node, err := f.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if node.header.Typeflag != tar.TypeSymlink {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return node.header.Linkname, nil
+}
+
+// Used to find the named file, following symlinks in its parent directories,
+// and the file itself when follow is true. Symlinks cannot resolve outside of
+// the image, as ".." at the root of the image is the root itself.
+func (f *ImageFS) lookup(op, name string, follow bool) (*imageFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	current := name
+	for links := 0; links <= maxSymlinks; links++ {
+		node, target, err := f.walk(current, follow)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if node != nil {
+			return node, nil
+		}
+		current = target
+	}
+	return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many levels of symbolic links")}
+}
+
+// Used to walk the named path, returning either the file, or the path to walk
+// next when a symlink is found.
+func (f *ImageFS) walk(name string, follow bool) (*imageFile, string, error) {
+	if name == "." {
+		return f.root, "", nil
+	}
+	node := f.root
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if node.children == nil {
+			return nil, "", fs.ErrNotExist
+		}
+		child, ok := node.children[part]
+		if !ok {
+			return nil, "", fs.ErrNotExist
+		}
+		last := i == len(parts)-1
+		if child.header.Typeflag == tar.TypeSymlink && (!last || follow) {
+			target := child.header.Linkname
+			if !path.IsAbs(target) {
+				target = path.Join("/", path.Join(parts[:i]...), target)
+			}
+			target = path.Join(target, path.Join(parts[i+1:]...))
+			return nil, cleanImagePath(target), nil
+		}
+		node = child
+	}
+	return node, "", nil
+}
+
+// Used to read the manifest of a saved image, then apply each of its layers.
+func (f *ImageFS) load(archive *os.File) error {
+	entries, err := indexTar(archive)
+	if err != nil {
+		return fmt.Errorf("failed to read image archive: %v", err)
+	}
+	manifestEntry, ok := entries["manifest.json"]
+	if !ok {
+		return fmt.Errorf("image archive has no manifest")
+	}
+	var manifest []struct {
+		Layers []string
+	}
+	err = json.NewDecoder(io.NewSectionReader(archive, manifestEntry.offset, manifestEntry.size)).Decode(&manifest)
+	if err != nil {
+		return fmt.Errorf("failed to read image manifest: %v", err)
+	}
+	if len(manifest) == 0 {
+		return fmt.Errorf("image archive has no images")
+	}
+	for _, name := range manifest[0].Layers {
+		entry, ok := entries[name]
+		if !ok {
+			return fmt.Errorf("image archive has no layer %s", name)
+		}
+		layer, err := f.openLayer(io.NewSectionReader(archive, entry.offset, entry.size))
+		if err != nil {
+			return fmt.Errorf("failed to read layer %s: %v", name, err)
+		}
+		err = f.applyLayer(layer)
+		if err != nil {
+			return fmt.Errorf("failed to apply layer %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Used to get an uncompressed view of a layer. Compressed layers are
+// decompressed to a temporary file, so that their files can be read later.
+func (f *ImageFS) openLayer(layer *io.SectionReader) (*io.SectionReader, error) {
+	magic := make([]byte, 2)
+	_, err := layer.ReadAt(magic, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if magic[0] != 0x1f || magic[1] != 0x8b {
+		return layer, nil
+	}
+	gz, err := gzip.NewReader(layer)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tmp, err := os.CreateTemp("", "dockerclient-layer-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	f.files = append(f.files, tmp)
+	size, err := io.Copy(tmp, gz)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(tmp, 0, size), nil
+}
+
+// Used to apply the changes of a layer to the filesystem. Whiteouts are applied
+// first, as they only remove files from the lower layers.
+func (f *ImageFS) applyLayer(layer *io.SectionReader) error {
+	type layerEntry struct {
+		header *tar.Header
+		offset int64
+	}
+	entries := make([]layerEntry, 0)
+	counter := &countingReader{r: layer}
+	tr := tar.NewReader(counter)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanImagePath(header.Name)
+		if name == "." {
+			continue
+		}
+		dir, base := path.Split(name)
+		dir = cleanImagePath(dir)
+		switch {
+		case base == whiteoutOpaque:
+			if node := f.ensureDir(dir); node != nil {
+				node.children = map[string]*imageFile{}
+			}
+		case strings.HasPrefix(base, whiteoutPrefix):
+			if node, _, err := f.walk(dir, false); err == nil && node != nil && node.children != nil {
+				delete(node.children, strings.TrimPrefix(base, whiteoutPrefix))
+			}
+		default:
+			header.Name = name
+			entries = append(entries, layerEntry{header: header, offset: counter.n})
+		}
+	}
+	for _, entry := range entries {
+		header := entry.header
+		dir, base := path.Split(header.Name)
+		parent := f.ensureDir(cleanImagePath(dir))
+		if parent == nil {
+			return fmt.Errorf("parent of %s is not a directory", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			// A directory replaces the metadata of an existing directory,
+			// but keeps the files of the lower layers.
+			if existing, ok := parent.children[base]; ok && existing.header.Typeflag == tar.TypeDir {
+				existing.header = header
+				continue
+			}
+			parent.children[base] = &imageFile{header: header, children: map[string]*imageFile{}}
+		case tar.TypeReg:
+			parent.children[base] = &imageFile{header: header, data: layer, offset: entry.offset}
+		case tar.TypeLink:
+			target, _, err := f.walk(cleanImagePath(header.Linkname), false)
+			if err != nil || target == nil {
+				return fmt.Errorf("hard link %s has no target %s", header.Name, header.Linkname)
+			}
+			linked := *target.header
+			linked.Name = header.Name
+			parent.children[base] = &imageFile{header: &linked, data: target.data, offset: target.offset}
+		default:
+			parent.children[base] = &imageFile{header: header}
+		}
+	}
+	return nil
+}
+
+// Used to get the named directory, creating it and its parents when they do
+// not exist, as layers are not required to include the parents of their files.
+// If the path is not a directory, nil is returned.
+func (f *ImageFS) ensureDir(name string) *imageFile {
+	node := f.root
+	if name == "." {
+		return node
+	}
+	for _, part := range strings.Split(name, "/") {
+		if node.children == nil {
+			return nil
+		}
+		child, ok := node.children[part]
+		if !ok {
+			child = newImageDir(path.Join(node.header.Name, part))
+			node.children[part] = child
+		}
+		node = child
+	}
+	if node.children == nil {
+		return nil
+	}
+	return node
+}
+
+func newImageDir(name string) *imageFile {
+	return &imageFile{
+		header: &tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name,
+			Mode:     0o755,
+			ModTime:  time.Unix(0, 0),
+		},
+		children: map[string]*imageFile{},
+	}
+}
+
+// Used to convert the name of a layer entry, such as "./usr/bin/" or "/etc",
+// to a path which is valid for fs.FS.
+func cleanImagePath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+type tarEntry struct {
+	offset int64
+	size   int64
+}
+
+// Used to find the offset and size of every regular file in a tar archive, so
+// that they can be read in any order.
+func indexTar(r io.ReaderAt) (map[string]tarEntry, error) {
+	entries := map[string]tarEntry{}
+	counter := &countingReader{r: io.NewSectionReader(r, 0, 1<<62)}
+	tr := tar.NewReader(counter)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			entries[path.Clean(header.Name)] = tarEntry{offset: counter.n, size: header.Size}
+		}
+	}
+}
+
+// Used to track the position of a tar reader in its archive. The tar reader
+// does not read ahead of the entry's content, so once Next returns, the
+// position is the offset of the entry's content.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// An open regular file, or any other file which is not a directory.
+type imageFileReader struct {
+	node *imageFile
+	*io.SectionReader
+}
+
+func (r *imageFileReader) Stat() (fs.FileInfo, error) {
+	return r.node.header.FileInfo(), nil
+}
+
+func (r *imageFileReader) Close() error {
+	return nil
+}
+
+// An open directory, which lists its files in name order.
+type imageDir struct {
+	node    *imageFile
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *imageDir) Stat() (fs.FileInfo, error) {
+	return d.node.header.FileInfo(), nil
+}
+
+func (d *imageDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.header.Name, Err: fs.ErrInvalid}
+}
+
+func (d *imageDir) Close() error {
+	return nil
+}
+
+func (d *imageDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		d.read = true
+		for _, child := range d.node.children {
+			d.entries = append(d.entries, fs.FileInfoToDirEntry(child.header.FileInfo()))
+		}
+		sort.Slice(d.entries, func(a, b int) bool {
+			return d.entries[a].Name() < d.entries[b].Name()
+		})
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,158 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type layerFile struct {
+	name     string
+	typeflag byte
+	mode     int64
+	content  string
+	linkname string
+}
+
+func writeLayer(t *testing.T, files []layerFile, compress bool) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, file := range files {
+		header := &tar.Header{
+			Name:     file.name,
+			Typeflag: file.typeflag,
+			Mode:     file.mode,
+			Size:     int64(len(file.content)),
+			Linkname: file.linkname,
+			Uid:      1000,
+		}
+		if file.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		assert.Nil(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(file.content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	if !compress {
+		return buf.Bytes()
+	}
+	compressed := new(bytes.Buffer)
+	gz := gzip.NewWriter(compressed)
+	_, err := gz.Write(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+	return compressed.Bytes()
+}
+
+// Used to create an archive in the format written by "docker save", with the
+// layers written after the manifest which references them.
+func writeImageArchive(t *testing.T, layers ...[]byte) []byte {
+	t.Helper()
+	names := make([]string, 0)
+	for i := range layers {
+		names = append(names, "blobs/sha256/layer"+strconv.Itoa(i))
+	}
+	manifest, err := json.Marshal([]map[string]any{{"Config": "blobs/sha256/config", "Layers": names}})
+	assert.Nil(t, err)
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	write := func(name string, content []byte) {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write(content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Name: "blobs/sha256/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	write("manifest.json", manifest)
+	for i, layer := range layers {
+		write(names[i], layer)
+	}
+	assert.Nil(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestFilesystem_GivenLayeredImage_ReconstructsFilesystem(t *testing.T) {
+	base := writeLayer(t, []layerFile{
+		{name: "./etc/", typeflag: tar.TypeDir, mode: 0o755},
+		{name: "./etc/config", typeflag: tar.TypeReg, mode: 0o644, content: "v1"},
+		{name: "./etc/removed", typeflag: tar.TypeReg, mode: 0o644, content: "gone"},
+		{name: "./cache/", typeflag: tar.TypeDir, mode: 0o755},
+		{name: "./cache/old", typeflag: tar.TypeReg, mode: 0o644, content: "old"},
+		{name: "./app/run.sh", typeflag: tar.TypeReg, mode: 0o755, content: "#!/bin/sh"},
+	}, false)
+	top := writeLayer(t, []layerFile{
+		{name: "etc/config", typeflag: tar.TypeReg, mode: 0o600, content: "v2"},
+		{name: "etc/.wh.removed", typeflag: tar.TypeReg},
+		{name: "cache/new", typeflag: tar.TypeReg, mode: 0o644, content: "new"},
+		{name: "cache/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "bin/start", typeflag: tar.TypeSymlink, linkname: "../app/run.sh"},
+		{name: "bin/run", typeflag: tar.TypeLink, linkname: "app/run.sh"},
+		{name: "etc/loop", typeflag: tar.TypeSymlink, linkname: "loop"},
+	}, true)
+	archive := writeImageArchive(t, base, top)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	fsys, err := c.Images.Filesystem(context.Background(), "app:1.0")
+	assert.Nil(t, err)
+	defer fsys.Close()
+
+	content, err := fs.ReadFile(fsys, "etc/config")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(content))
+	info, err := fs.Stat(fsys, "etc/config")
+	assert.Nil(t, err)
+	assert.Equal(t, fs.FileMode(0o600), info.Mode().Perm())
+	assert.Equal(t, 1000, info.Sys().(*tar.Header).Uid)
+	_, err = fs.Stat(fsys, "etc/removed")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	entries, err := fs.ReadDir(fsys, "cache")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "new", entries[0].Name())
+	content, err = fs.ReadFile(fsys, "bin/start")
+	assert.Nil(t, err)
+	assert.Equal(t, "#!/bin/sh", string(content))
+	content, err = fs.ReadFile(fsys, "bin/run")
+	assert.Nil(t, err)
+	assert.Equal(t, "#!/bin/sh", string(content))
+	target, err := fsys.ReadLink("bin/start")
+	assert.Nil(t, err)
+	assert.Equal(t, "../app/run.sh", target)
+	info, err = fsys.Lstat("bin/start")
+	assert.Nil(t, err)
+	assert.Equal(t, fs.ModeSymlink, info.Mode().Type())
+	_, err = fs.Stat(fsys, "etc/loop")
+	assert.ErrorContains(t, err, "too many levels of symbolic links")
+}
+
+func TestFilesystem_GivenImage_ImplementsFS(t *testing.T) {
+	layer := writeLayer(t, []layerFile{
+		{name: "app/", typeflag: tar.TypeDir, mode: 0o755},
+		{name: "app/main", typeflag: tar.TypeReg, mode: 0o755, content: "binary"},
+		{name: "etc/hostname", typeflag: tar.TypeReg, mode: 0o644, content: "app"},
+	}, false)
+	fsys := &ImageFS{root: newImageDir(".")}
+	assert.Nil(t, fsys.applyLayer(bytesSection(layer)))
+
+	assert.Nil(t, fstest.TestFS(fsys, "app/main", "etc/hostname"))
+}
+
+func bytesSection(b []byte) *io.SectionReader {
+	return io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b)))
+}
@@ -0,0 +1,140 @@
+package dockerclient
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// The length which instructions are truncated to by ImageHistory.WriteText.
+const historyInstructionWidth = 80
+
+// ImageHistory describes the layers of an image, in the order they were
+// created, from the base image's first layer to the image's last.
+type ImageHistory struct {
+	Image  string       `json:"image"`
+	Layers []ImageLayer `json:"layers"`
+	// Size is the total size of the image's layers, in bytes.
+	Size int64 `json:"size"`
+}
+
+// ImageLayer describes a single step in the history of an image. Steps which
+// only change the image's configuration, such as ENV, have a size of 0.
+type ImageLayer struct {
+	// ID is the ID of the image created by the step. It is "<missing>" for
+	// steps which were not built locally, such as those of a pulled base image.
+	ID string `json:"id"`
+	// Instruction is the Dockerfile instruction of the step, such as
+	// "RUN make build".
+	Instruction string `json:"instruction"`
+	// CreatedBy is the command recorded by the daemon for the step, which
+	// Instruction is derived from.
+	CreatedBy string    `json:"createdBy"`
+	Created   time.Time `json:"created"`
+	Comment   string    `json:"comment,omitempty"`
+	// Size is the size of the files added by the step, in bytes, and
+	// CumulativeSize is the size of the image once the step was complete.
+	Size           int64 `json:"size"`
+	CumulativeSize int64 `json:"cumulativeSize"`
+}
+
+// History is used to get the layers of the image with the given name or ID,
+// with the instruction which created each layer and its size.
+func (i ImageOperations) History(ctx context.Context, ref string) (*ImageHistory, error) {
+	items, err := i.cli.ImageHistory(ctx, ref)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	history := &ImageHistory{
+		Image:  ref,
+		Layers: make([]ImageLayer, 0, len(items)),
+	}
+	// The daemon lists the most recent step first.
+	for _, item := range slices.Backward(items) {
+		history.Size += item.Size
+		history.Layers = append(history.Layers, ImageLayer{
+			ID:             item.ID,
+			Instruction:    historyInstruction(item.CreatedBy),
+			CreatedBy:      item.CreatedBy,
+			Created:        time.Unix(item.Created, 0),
+			Comment:        item.Comment,
+			Size:           item.Size,
+			CumulativeSize: history.Size,
+		})
+	}
+	return history, nil
+}
+
+// Largest is used to get the n largest layers of the image, largest first. A
+// negative n returns no layers.
+func (h *ImageHistory) Largest(n int) []ImageLayer {
+	layers := slices.Clone(h.Layers)
+	slices.SortStableFunc(layers, func(a, b ImageLayer) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	return layers[:min(max(n, 0), len(layers))]
+}
+
+// WriteText is used to write the history as a table, with a row for each
+// layer and a final row with the image's total size.
+func (h *ImageHistory) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSIZE\tCUMULATIVE\tINSTRUCTION")
+	for n, layer := range h.Layers {
+		instruction := layer.Instruction
+		// Truncated by runes, so that multi-byte characters are not split.
+		if runes := []rune(instruction); len(runes) > historyInstructionWidth {
+			instruction = string(runes[:historyInstructionWidth-3]) + "..."
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", n+1, units.HumanSize(float64(layer.Size)), units.HumanSize(float64(layer.CumulativeSize)), instruction)
+	}
+	fmt.Fprintf(tw, "\t%s\t\ttotal\n", units.HumanSize(float64(h.Size)))
+	return tw.Flush()
+}
+
+// WriteJSON is used to write the history as an indented JSON document.
+func (h *ImageHistory) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(h)
+}
+
+// Used to convert the command recorded for a step to the Dockerfile instruction
+// which created it. The legacy builder records RUN instructions as shell
+// commands, prefixed with the build arguments they used, and other instructions
+// as "#(nop)" commands. BuildKit records the instruction, with a comment.
+func historyInstruction(createdBy string) string {
+	s := strings.TrimSpace(createdBy)
+	s = strings.TrimSpace(strings.TrimSuffix(s, "# buildkit"))
+	s, run := strings.CutPrefix(s, "RUN ")
+	// Build arguments are recorded as "|2 A=1 B=2 /bin/sh -c make".
+	if after, ok := strings.CutPrefix(s, "|"); ok {
+		count, rest, _ := strings.Cut(after, " ")
+		n, err := strconv.Atoi(count)
+		if err == nil && n > 0 {
+			fields := strings.SplitN(rest, " ", n+1)
+			if len(fields) == n+1 {
+				s = fields[n]
+			}
+		}
+	}
+	if after, ok := strings.CutPrefix(s, "/bin/sh -c #(nop) "); ok {
+		return strings.TrimSpace(after)
+	}
+	if after, ok := strings.CutPrefix(s, "/bin/sh -c "); ok {
+		return "RUN " + after
+	}
+	if run {
+		return "RUN " + s
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package dockerclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_GivenImage_ReturnsLayersOldestFirst(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"Id": "sha256:5b2c", "Created": 1714557600, "CreatedBy": "CMD [\"/app\"]", "Size": 0},
+			{"Id": "<missing>", "Created": 1714557600, "CreatedBy": "RUN /bin/sh -c apk add curl # buildkit", "Size": 3000000},
+			{"Id": "<missing>", "Created": 1714471200, "CreatedBy": "/bin/sh -c #(nop) ADD file:4b82 in / ", "Size": 7000000}
+		]`))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	history, err := c.Images.History(context.Background(), "app:1.0")
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10000000), history.Size)
+	assert.Len(t, history.Layers, 3)
+	assert.Equal(t, "ADD file:4b82 in /", history.Layers[0].Instruction)
+	assert.Equal(t, "RUN apk add curl", history.Layers[1].Instruction)
+	assert.Equal(t, int64(10000000), history.Layers[1].CumulativeSize)
+	assert.Equal(t, "sha256:5b2c", history.Layers[2].ID)
+	assert.Equal(t, "RUN apk add curl", history.Largest(2)[1].Instruction)
+}
+
+func TestHistoryInstruction_GivenCreatedBy_ReturnsInstruction(t *testing.T) {
+	tests := map[string]string{
+		`/bin/sh -c #(nop)  ENV PORT=8080`:                  "ENV PORT=8080",
+		`/bin/sh -c make build`:                             "RUN make build",
+		`|2 VERSION=1.0 TARGET=app /bin/sh -c make $TARGET`: "RUN make $TARGET",
+		`RUN |1 VERSION=1.0 /bin/sh -c make # buildkit`:     "RUN make",
+		`COPY /out/app /usr/local/bin/app # buildkit`:       "COPY /out/app /usr/local/bin/app",
+		`RUN ["/app", "migrate"] # buildkit`:                "RUN [\"/app\", \"migrate\"]",
+		`WORKDIR /src`:                                      "WORKDIR /src",
+		`|-1 x`:                                             "|-1 x",
+		`|0 /bin/sh -c make`:                                "|0 /bin/sh -c make",
+	}
+	for createdBy, expected := range tests {
+		assert.Equal(t, expected, historyInstruction(createdBy), createdBy)
+	}
+}
+
+func TestImageHistoryWrite_GivenHistory_RendersTextAndJSON(t *testing.T) {
+	history := &ImageHistory{
+		Image: "app:1.0",
+		Layers: []ImageLayer{
+			{ID: "<missing>", Instruction: "ADD file:4b82 in /", Size: 7000000, CumulativeSize: 7000000},
+			{ID: "sha256:5b2c", Instruction: "RUN apk add curl", Size: 3000000, CumulativeSize: 10000000},
+		},
+		Size: 10000000,
+	}
+
+	text := new(bytes.Buffer)
+	assert.Nil(t, history.WriteText(text))
+	assert.Equal(t, `#  SIZE  CUMULATIVE  INSTRUCTION
+1  7MB   7MB         ADD file:4b82 in /
+2  3MB   10MB        RUN apk add curl
+   10MB              total
+`, text.String())
+
+	encoded := new(bytes.Buffer)
+	assert.Nil(t, history.WriteJSON(encoded))
+	var decoded ImageHistory
+	assert.Nil(t, json.Unmarshal(encoded.Bytes(), &decoded))
+	assert.Equal(t, history.Layers[1].Instruction, decoded.Layers[1].Instruction)
+	assert.Equal(t, history.Size, decoded.Size)
+}
+
+func TestImageHistoryLargest_GivenNegativeCount_ReturnsNoLayers(t *testing.T) {
+	history := &ImageHistory{Layers: []ImageLayer{{Size: 10}, {Size: 20}}}
+
+	assert.Empty(t, history.Largest(-1))
+	assert.Len(t, history.Largest(5), 2)
+}
+
+func TestImageHistoryWriteText_GivenLongMultiByteInstruction_TruncatesOnRunes(t *testing.T) {
+	history := &ImageHistory{Layers: []ImageLayer{{Instruction: "RUN echo " + strings.Repeat("é", 100)}}}
+	buf := new(bytes.Buffer)
+
+	err := history.WriteText(buf)
+
+	assert.Nil(t, err)
+	assert.True(t, utf8.Valid(buf.Bytes()))
+	assert.Contains(t, buf.String(), "RUN echo "+strings.Repeat("é", historyInstructionWidth-12)+"...")
+}
@@ -2,136 +2,988 @@ package dockerclient
 
 import (
 	"archive/tar"
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-connections/nat"
+	"github.com/moby/patternmatcher"
 
+	"github.com/james226/dockerclient/auth"
 	"github.com/james226/dockerclient/options"
+	"github.com/james226/dockerclient/progress"
 )
 
 type Image struct {
 	Name string
+	// ID is the content addressable ID of the image, such as "sha256:4b82...".
+	// It is only known once the image has been built or inspected.
+	ID string
+	// Digest is the registry digest of the image, such as "app@sha256:9f1c...".
+	// It is empty for images which have not been pushed to or pulled from a registry.
+	Digest string
+	// Tags and Digests are every name and registry digest of the image.
+	Tags    []string
+	Digests []string
+	// Env holds the image's environment variables, in the format of "foo=bar".
+	Env          []string
+	Entrypoint   []string
+	Cmd          []string
+	WorkingDir   string
+	User         string
+	ExposedPorts []nat.Port
+	Labels       map[string]string
+	// Size is the size of the image's layers, in bytes.
+	Size         int64
+	Architecture string
+	OS           string
+	Created      time.Time
+}
+
+// ImagePruneReport describes the images removed by ImageOperations.Prune.
+type ImagePruneReport struct {
+	// ImagesDeleted are the IDs of the images which were deleted.
+	ImagesDeleted []string
+	// ImagesUntagged are the names of the images which were untagged.
+	ImagesUntagged []string
+	SpaceReclaimed uint64
 }
 
 type ImageOperations struct {
-	cli *client.Client
+	cli      *client.Client
+	logger   *slog.Logger
+	output   io.Writer
+	rewrites imageRewrites
+}
+
+// Pull is used to pull an image from its registry. Unless credentials are
+// given in the options, the credentials configured for the Docker CLI are used,
+// including those of credential helpers. The registry mirrors and tag rewrites
+// configured for the client are applied to name, and the returned image is
+// named by the rewritten reference.
+func (i ImageOperations) Pull(ctx context.Context, name string, opts ...*options.PullImageOptions) (*Image, error) {
+	opt := options.PullImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	rewritten, err := i.rewrite(name)
+	if err != nil {
+		return nil, &PullError{Image: name, Err: err}
+	}
+	return i.pull(ctx, rewritten, opt)
 }
 
-func (i ImageOperations) Pull(ctx context.Context, name string) (*Image, error) {
-	reader, err := i.cli.ImagePull(ctx, name, image.PullOptions{})
+// Used to pull an image without applying the client's rewrites.
+func (i ImageOperations) pull(ctx context.Context, name string, opt *options.PullImageOptions) (*Image, error) {
+	ref, err := ParseReference(name)
+	if err != nil {
+		return nil, &PullError{Image: name, Err: err}
+	}
+	name = ref.String()
+	registryAuth, err := pullAuth(ctx, name, opt)
+	if err != nil {
+		return nil, &PullError{Image: name, Err: err}
+	}
+	pullOptions := image.PullOptions{RegistryAuth: registryAuth}
+	if platform, ok := opt.Platform(); ok {
+		pullOptions.Platform = platform
+	}
+	reader, err := i.cli.ImagePull(ctx, name, pullOptions)
+	if err != nil {
+		return nil, &PullError{Image: name, Err: dockerError(err)}
+	}
+	defer reader.Close()
+	// Closing the stream unblocks the decoder as soon as the context is
+	// cancelled, rather than when the daemon next reports progress.
+	stop := context.AfterFunc(ctx, func() {
+		reader.Close()
+	})
+	defer stop()
+
+	i.logger.Debug("Pulling image", "image", name, "phase", "pull")
+	digest, err := i.readPullOutput(reader, name, opt)
+	if ctx.Err() != nil {
+		return nil, &PullError{Image: name, Err: ctx.Err()}
+	}
 	if err != nil {
+		i.logger.Error("Failed to pull image", "image", name, "phase", "pull", "error", err)
+		return nil, &PullError{Image: name, Err: err}
+	}
+	i.logger.Debug("Pulled image", "image", name, "phase", "pull", "digest", digest)
+	return &Image{Name: name, Digest: digestReference(name, digest)}, nil
+}
+
+// Used to decode the JSON messages streamed while pulling an image, reporting
+// each as a progress event. The digest of the pulled image is returned.
+func (i ImageOperations) readPullOutput(r io.Reader, name string, opt *options.PullImageOptions) (string, error) {
+	onProgress, _ := opt.Progress()
+	return i.readLayerOutput(r, name, "pull", opt.Quiet(), func(layerID, status string, current, total int64) {
+		if onProgress != nil {
+			onProgress(progress.PullEvent{LayerID: layerID, Status: status, Current: current, Total: total})
+		}
+	})
+}
+
+// Used to decode the JSON messages streamed while pushing an image, reporting
+// each as a progress event. The digest of the pushed image is returned.
+func (i ImageOperations) readPushOutput(r io.Reader, name string, opt *options.PushImageOptions) (string, error) {
+	onProgress, _ := opt.Progress()
+	return i.readLayerOutput(r, name, "push", opt.Quiet(), func(layerID, status string, current, total int64) {
+		if onProgress != nil {
+			onProgress(progress.PushEvent{LayerID: layerID, Status: status, Current: current, Total: total})
+		}
+	})
+}
+
+// Used to decode the per-layer JSON messages streamed while pulling or pushing
+// an image, passing each to report and writing status changes to the output.
+// Errors reported in the stream are returned, as is the image's digest.
+func (i ImageOperations) readLayerOutput(r io.Reader, name, action string, quiet bool, report func(layerID, status string, current, total int64)) (string, error) {
+	digest := ""
+	decoder := json.NewDecoder(r)
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read image %s output: %v", action, err)
+		}
+		errorMessage := message.ErrorMessage
+		if message.Error != nil {
+			errorMessage = message.Error.Message
+		}
+		if errorMessage != "" {
+			return "", errors.New(errorMessage)
+		}
+		if after, ok := strings.CutPrefix(message.Status, "Digest: "); ok {
+			digest = after
+		}
+		if message.Aux != nil {
+			var aux struct {
+				Digest string `json:"Digest"`
+			}
+			if json.Unmarshal(*message.Aux, &aux) == nil && aux.Digest != "" {
+				digest = aux.Digest
+			}
+			continue
+		}
+		var current, total int64
+		if message.Progress != nil {
+			current, total = message.Progress.Current, message.Progress.Total
+		}
+		report(message.ID, message.Status, current, total)
+		// Only status changes are written, as a layer reports its progress
+		// many times a second while it is transferred.
+		if quiet || current > 0 {
+			continue
+		}
+		if message.ID != "" {
+			fmt.Fprintf(i.output, "[%s]: %s: %s\n", name, message.ID, message.Status)
+		} else {
+			fmt.Fprintf(i.output, "[%s]: %s\n", name, message.Status)
+		}
+	}
+	return digest, nil
+}
+
+// Used to get the digest reference of a pulled or pushed image, such as
+// "postgres@sha256:9f1c...", in the same format as the image's RepoDigests.
+func digestReference(name, digest string) string {
+	if digest == "" {
+		return ""
+	}
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return ""
+	}
+	return reference.FamiliarName(named) + "@" + digest
+}
+
+// Ensure is used to make sure an image exists locally, according to the given
+// policy. With PullIfNotPresent, the image is only pulled when it is missing.
+// With PullNever, an error matching ErrNotFound is returned when it is missing.
+// The image is looked for under ref, and then under the reference given by the
+// client's registry mirrors and tag rewrites, which is the reference it is
+// pulled as.
+func (i ImageOperations) Ensure(ctx context.Context, ref string, policy options.PullPolicy, opts ...*options.PullImageOptions) (*Image, error) {
+	opt := options.PullImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if policy != options.PullAlways {
+		found, err := i.findLocal(ctx, ref)
+		if err == nil {
+			return found, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if policy == options.PullNever {
+			return nil, fmt.Errorf("image '%s' does not exist locally and the pull policy is %s: %w", ref, policy, err)
+		}
+		i.logger.Debug("Image not found locally", "image", ref, "phase", "pull")
+	}
+	rewritten, err := i.rewrite(ref)
+	if err != nil {
+		return nil, &PullError{Image: ref, Err: err}
+	}
+	return i.pull(ctx, rewritten, opt)
+}
+
+// Used to find an image locally under ref, or else under the reference given by
+// the client's rewrites, such as when it was pulled through a mirror. The error
+// for ref is returned when the image exists under neither.
+func (i ImageOperations) findLocal(ctx context.Context, ref string) (*Image, error) {
+	found, err := i.Get(ctx, ref)
+	if !errors.Is(err, ErrNotFound) {
+		return found, err
+	}
+	rewritten, rewriteErr := i.rewrites.rewrite(ref)
+	if rewriteErr != nil || rewritten == ref {
 		return nil, err
 	}
+	found, rewrittenErr := i.Get(ctx, rewritten)
+	if errors.Is(rewrittenErr, ErrNotFound) {
+		return nil, err
+	}
+	return found, rewrittenErr
+}
+
+// Used to apply the registry mirrors and tag rewrites configured for the client
+// to an image reference.
+func (i ImageOperations) rewrite(ref string) (string, error) {
+	rewritten, err := i.rewrites.rewrite(ref)
+	if err != nil {
+		return "", err
+	}
+	if rewritten != ref {
+		i.logger.Debug("Rewrote image reference", "image", ref, "rewritten", rewritten)
+	}
+	return rewritten, nil
+}
 
+// Push is used to push an image to its registry as ref, such as
+// "registry.example.com/team/app:1.0". When ref differs from the image's name,
+// the image is first tagged as ref. Unless credentials are given in the options,
+// the credentials configured for the Docker CLI are used.
+func (i ImageOperations) Push(ctx context.Context, img *Image, ref string, opts ...*options.PushImageOptions) (*Image, error) {
+	opt := options.PushImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if ref == "" {
+		ref = img.Name
+	}
+	if ref != img.Name {
+		source := img.ID
+		if source == "" {
+			source = img.Name
+		}
+		err := i.Tag(ctx, source, ref)
+		if err != nil {
+			return nil, &PushError{Image: ref, Err: err}
+		}
+	}
+	registryAuth, err := pushAuth(ctx, ref, opt)
+	if err != nil {
+		return nil, &PushError{Image: ref, Err: err}
+	}
+	reader, err := i.cli.ImagePush(ctx, ref, image.PushOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return nil, &PushError{Image: ref, Err: dockerError(err)}
+	}
 	defer reader.Close()
-	_, err = io.Copy(os.Stdout, reader)
-	return &Image{name}, err
+	stop := context.AfterFunc(ctx, func() {
+		reader.Close()
+	})
+	defer stop()
+
+	i.logger.Debug("Pushing image", "image", ref, "phase", "push")
+	digest, err := i.readPushOutput(reader, ref, opt)
+	if ctx.Err() != nil {
+		return nil, &PushError{Image: ref, Err: ctx.Err()}
+	}
+	if err != nil {
+		i.logger.Error("Failed to push image", "image", ref, "phase", "push", "error", err)
+		return nil, &PushError{Image: ref, Err: err}
+	}
+	i.logger.Debug("Pushed image", "image", ref, "phase", "push", "digest", digest)
+	return &Image{Name: ref, ID: img.ID, Digest: digestReference(ref, digest)}, nil
+}
+
+// Used to get the encoded X-Registry-Auth header for pulling the image.
+func pullAuth(ctx context.Context, name string, opt *options.PullImageOptions) (string, error) {
+	creds, ok := opt.Credentials()
+	return registryAuth(ctx, name, creds, ok, opt.DockerConfig())
+}
+
+// Used to get the encoded X-Registry-Auth header for pushing the image.
+func pushAuth(ctx context.Context, name string, opt *options.PushImageOptions) (string, error) {
+	creds, ok := opt.Credentials()
+	return registryAuth(ctx, name, creds, ok, opt.DockerConfig())
+}
+
+// Used to encode the given credentials for the registry of the named image. If
+// no credentials are given, those configured for the Docker CLI are used.
+func registryAuth(ctx context.Context, name string, creds registry.AuthConfig, ok bool, dockerConfig string) (string, error) {
+	if !ok {
+		var err error
+		creds, err = auth.ForImage(ctx, dockerConfig, name)
+		if err != nil {
+			return "", err
+		}
+	} else if creds.ServerAddress == "" {
+		host, err := auth.RegistryHost(name)
+		if err != nil {
+			return "", err
+		}
+		creds.ServerAddress = auth.ServerAddress(host)
+	}
+	return auth.Encode(creds)
 }
 
 func (i ImageOperations) Build(ctx context.Context, name string, path string, opts ...*options.BuildImageOptions) (*Image, error) {
-	buf := new(bytes.Buffer)
-	tw := tar.NewWriter(buf)
-	defer tw.Close()
-	err := loadBuildContext(path, "", tw)
+	opt := options.BuildImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build context: %v", err)
+	}
+	content, hasContent, err := opt.DockerfileContent()
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := buildContextMatcher(os.DirFS(path), opt.Dockerfile(), opt.Excludes())
 	if err != nil {
 		return nil, err
 	}
-	contextReader := bytes.NewReader(buf.Bytes())
+	// The context is streamed to the daemon as it is read from disk, so that
+	// large contexts are never held in memory.
+	dockerfilePath := buildContextDockerfile(opt)
+	buildContext := streamTar(func(tw *tar.Writer) error {
+		if !hasContent {
+			return loadBuildContext(path, tw, excludes, nil)
+		}
+		err := writeBuildContextFile(tw, dockerfilePath, []byte(content))
+		if err != nil {
+			return err
+		}
+		return loadBuildContext(path, tw, excludes, func(name string) bool {
+			return name == dockerfilePath
+		})
+	})
+	defer buildContext.Close()
+	return i.build(ctx, name, buildContext, opt)
+}
+
+// BuildFromFS is used to build an image using the contents of fsys, such as an
+// embed.FS, as the build context. The same as Build, the .dockerignore file of
+// fsys is honoured.
+func (i ImageOperations) BuildFromFS(ctx context.Context, name string, fsys fs.FS, opts ...*options.BuildImageOptions) (*Image, error) {
+	return i.BuildFromDockerfile(ctx, name, "", fsys, opts...)
+}
+
+// BuildFromDockerfile is used to build an image from the given Dockerfile
+// content, using the contents of fsys as the rest of the build context. The
+// Dockerfile is added to the context under the configured Dockerfile path,
+// replacing any existing file. If the Dockerfile does not COPY or ADD any
+// files, fsys may be nil. If dockerfile is empty, the Dockerfile is read from fsys.
+func (i ImageOperations) BuildFromDockerfile(ctx context.Context, name, dockerfile string, fsys fs.FS, opts ...*options.BuildImageOptions) (*Image, error) {
+	opt := options.BuildImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if dockerfile == "" {
+		content, _, err := opt.DockerfileContent()
+		if err != nil {
+			return nil, err
+		}
+		dockerfile = content
+	}
+	var excludes *patternmatcher.PatternMatcher
+	if fsys != nil {
+		var err error
+		excludes, err = buildContextMatcher(fsys, opt.Dockerfile(), opt.Excludes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	buildContext := streamTar(func(tw *tar.Writer) error {
+		dockerfilePath := buildContextDockerfile(opt)
+		if dockerfile != "" {
+			err := writeBuildContextFile(tw, dockerfilePath, []byte(dockerfile))
+			if err != nil {
+				return err
+			}
+		}
+		if fsys == nil {
+			return nil
+		}
+		return loadBuildContextFS(fsys, tw, excludes, func(name string) bool {
+			return dockerfile != "" && name == dockerfilePath
+		})
+	})
+	defer buildContext.Close()
+	return i.build(ctx, name, buildContext, opt)
+}
+
+// BuildFromFiles is used to build an image from a build context made up of the
+// given files, keyed by their slash separated path within the context. Each file
+// is given a mode of 0644. An error is returned for paths outside of the
+// context, such as "../config" or "/etc/config".
+func (i ImageOperations) BuildFromFiles(ctx context.Context, name string, files map[string][]byte, opts ...*options.BuildImageOptions) (*Image, error) {
+	opt := options.BuildImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	names := make([]string, 0, len(files))
+	for file := range files {
+		clean := path.Clean(file)
+		if clean == "." || !fs.ValidPath(clean) {
+			return nil, fmt.Errorf("invalid build context file '%s': paths must be relative and within the context", file)
+		}
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	buildContext := streamTar(func(tw *tar.Writer) error {
+		for _, file := range names {
+			err := writeBuildContextFile(tw, path.Clean(file), files[file])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	defer buildContext.Close()
+	return i.build(ctx, name, buildContext, opt)
+}
+
+// BuildFromTar is used to build an image from a prepared build context, in the
+// form of a tar archive. The archive is sent to the daemon as is, so exclusions
+// are not applied.
+func (i ImageOperations) BuildFromTar(ctx context.Context, name string, buildContext io.Reader, opts ...*options.BuildImageOptions) (*Image, error) {
 	opt := options.BuildImage()
 	if len(opts) > 0 {
 		opt = opts[0]
 	}
+	return i.build(ctx, name, buildContext, opt)
+}
+
+// Used to send the build context to the daemon, wait for the build to complete
+// and then inspect the built image.
+func (i ImageOperations) build(ctx context.Context, name string, buildContext io.Reader, opt *options.BuildImageOptions) (*Image, error) {
+	ref, err := ParseReference(name)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Digest != "" {
+		return nil, fmt.Errorf("invalid image name '%s': built images cannot be named by digest", name)
+	}
+	name = ref.String()
 	buildOptions := types.ImageBuildOptions{
-		Context:    bytes.NewReader(buf.Bytes()),
-		Dockerfile: opt.Dockerfile(),
-		Tags:       []string{name},
-		Remove:     true,
+		Dockerfile:  opt.Dockerfile(),
+		Tags:        append([]string{name}, opt.Tags()...),
+		Remove:      true,
+		BuildArgs:   opt.BuildArgs(),
+		Target:      opt.Target(),
+		Labels:      opt.Labels(),
+		NoCache:     opt.NoCache(),
+		PullParent:  opt.PullParent(),
+		CacheFrom:   opt.CacheFrom(),
+		NetworkMode: opt.NetworkMode(),
+		ExtraHosts:  opt.ExtraHosts(),
+		ShmSize:     opt.ShmSize(),
 	}
 	platform, ok := opt.Platform()
 	if ok {
 		buildOptions.Platform = platform
 	}
-	build, err := i.cli.ImageBuild(ctx, contextReader, buildOptions)
+	build, err := i.cli.ImageBuild(ctx, buildContext, buildOptions)
 	if err != nil {
-		return nil, err
+		return nil, dockerError(err)
 	}
 	defer build.Body.Close()
-	err = logBuildOutput(build.Body, name)
+	onProgress, _ := opt.Progress()
+	id, err := i.readBuildOutput(build.Body, name, onProgress)
+	if err != nil {
+		return nil, err
+	}
+	built, err := i.Get(ctx, name)
 	if err != nil {
 		return nil, err
 	}
-	return &Image{Name: name}, nil
+	if id != "" {
+		built.ID = id
+	}
+	return built, nil
 }
 
-// Used to recursively load files from the specified path a .tar file.
-func loadBuildContext(path, relativePath string, tw *tar.Writer) error {
-	entries, err := os.ReadDir(path)
+// Get is used to inspect the image with the given name or ID, such as
+// "postgres:16", returning its configuration and metadata.
+func (i ImageOperations) Get(ctx context.Context, ref string) (*Image, error) {
+	inspect, err := i.cli.ImageInspect(ctx, ref)
 	if err != nil {
-		return fmt.Errorf("failed to read dir: %v", err)
+		return nil, dockerError(err)
 	}
-	for _, entry := range entries {
-		if entry.IsDir() {
-			err = loadBuildContext(filepath.Join(path, entry.Name()), filepath.Join(relativePath, entry.Name()), tw)
-			if err != nil {
-				return err
-			}
-			continue
+	return inspectedImage(ref, inspect), nil
+}
+
+// List is used to list the images which match the given filters. If no
+// filters are given, all tagged images are returned.
+func (i ImageOperations) List(ctx context.Context, opts ...*options.ListImagesOptions) ([]*Image, error) {
+	opt := options.ListImages()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	args := labelFilters(opt.Labels())
+	for _, ref := range opt.References() {
+		args.Add("reference", ref)
+	}
+	if dangling, ok := opt.Dangling(); ok {
+		args.Add("dangling", strconv.FormatBool(dangling))
+	}
+	summaries, err := i.cli.ImageList(ctx, image.ListOptions{
+		All:     opt.All(),
+		Filters: args,
+	})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	images := make([]*Image, 0, len(summaries))
+	for _, summary := range summaries {
+		images = append(images, summaryImage(summary))
+	}
+	return images, nil
+}
+
+// Tag is used to give the source image, a name or ID, the additional target
+// name, such as "registry.example.com/app:1.0".
+func (i ImageOperations) Tag(ctx context.Context, source, target string) error {
+	err := i.cli.ImageTag(ctx, source, target)
+	if err != nil {
+		return dockerError(err)
+	}
+	i.logger.Debug("Tagged image", "image", source, "tag", target)
+	return nil
+}
+
+// Remove is used to remove the image with the given name or ID. When the image
+// has other names, only the given name is removed, unless forced by the options.
+func (i ImageOperations) Remove(ctx context.Context, ref string, opts ...*options.RemoveImageOptions) error {
+	opt := options.RemoveImage()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	_, err := i.cli.ImageRemove(ctx, ref, image.RemoveOptions{
+		Force:         opt.Force(),
+		PruneChildren: opt.PruneChildren(),
+	})
+	return dockerError(err)
+}
+
+// Prune is used to remove unused images which have all of the given labels.
+// If dangling is true, only images without a name are removed, otherwise all
+// images not used by a container are. If until is non-zero, only images
+// created more than until ago are removed.
+func (i ImageOperations) Prune(ctx context.Context, dangling bool, until time.Duration, labels map[string]string) (*ImagePruneReport, error) {
+	args := labelFilters(labels)
+	args.Add("dangling", strconv.FormatBool(dangling))
+	if until > 0 {
+		args.Add("until", until.String())
+	}
+	report, err := i.cli.ImagesPrune(ctx, args)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	pruned := &ImagePruneReport{
+		ImagesDeleted:  make([]string, 0),
+		ImagesUntagged: make([]string, 0),
+		SpaceReclaimed: report.SpaceReclaimed,
+	}
+	for _, item := range report.ImagesDeleted {
+		if item.Deleted != "" {
+			pruned.ImagesDeleted = append(pruned.ImagesDeleted, item.Deleted)
+		}
+		if item.Untagged != "" {
+			pruned.ImagesUntagged = append(pruned.ImagesUntagged, item.Untagged)
+		}
+	}
+	return pruned, nil
+}
+
+// Save is used to export the given images, including their layers and tags,
+// as a tar archive written to w. The archive can be loaded with Load.
+func (i ImageOperations) Save(ctx context.Context, w io.Writer, refs ...string) error {
+	reader, err := i.cli.ImageSave(ctx, refs)
+	if err != nil {
+		return dockerError(err)
+	}
+	defer reader.Close()
+	stop := context.AfterFunc(ctx, func() {
+		reader.Close()
+	})
+	defer stop()
+	_, err = io.Copy(w, reader)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save images: %v", err)
+	}
+	i.logger.Debug("Saved images", "images", refs, "phase", "save")
+	return nil
+}
+
+// Load is used to import the images of a tar archive, such as one written by
+// Save. The names of the loaded images are returned, or their IDs for images
+// without a name.
+func (i ImageOperations) Load(ctx context.Context, r io.Reader) ([]string, error) {
+	resp, err := i.cli.ImageLoad(ctx, r, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	defer resp.Body.Close()
+	loaded, err := readLoadOutput(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	i.logger.Debug("Loaded images", "images", loaded, "phase", "load")
+	return loaded, nil
+}
+
+// Used to decode the JSON messages streamed while loading images, returning
+// the names or IDs of the loaded images.
+func readLoadOutput(r io.Reader) ([]string, error) {
+	loaded := make([]string, 0)
+	decoder := json.NewDecoder(r)
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			break
 		}
-		filename := filepath.Join(path, entry.Name())
-		rdr, err := os.Open(filename)
 		if err != nil {
-			return fmt.Errorf("failed to open %s: %v", filename, err)
+			return nil, fmt.Errorf("failed to read image load output: %v", err)
+		}
+		errorMessage := message.ErrorMessage
+		if message.Error != nil {
+			errorMessage = message.Error.Message
+		}
+		if errorMessage != "" {
+			return nil, fmt.Errorf("failed to load images: %s", errorMessage)
+		}
+		for _, line := range strings.Split(message.Stream, "\n") {
+			if ref, ok := strings.CutPrefix(strings.TrimSpace(line), "Loaded image ID: "); ok {
+				loaded = append(loaded, ref)
+			} else if ref, ok := strings.CutPrefix(strings.TrimSpace(line), "Loaded image: "); ok {
+				loaded = append(loaded, ref)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+func inspectedImage(name string, inspect image.InspectResponse) *Image {
+	img := &Image{
+		Name:         name,
+		ID:           inspect.ID,
+		Tags:         inspect.RepoTags,
+		Digests:      inspect.RepoDigests,
+		Size:         inspect.Size,
+		Architecture: inspect.Architecture,
+		OS:           inspect.Os,
+	}
+	if len(inspect.RepoDigests) > 0 {
+		img.Digest = inspect.RepoDigests[0]
+	}
+	img.Created, _ = time.Parse(time.RFC3339Nano, inspect.Created)
+	if inspect.Config != nil {
+		img.Env = inspect.Config.Env
+		img.Entrypoint = inspect.Config.Entrypoint
+		img.Cmd = inspect.Config.Cmd
+		img.WorkingDir = inspect.Config.WorkingDir
+		img.User = inspect.Config.User
+		img.Labels = inspect.Config.Labels
+		for port := range inspect.Config.ExposedPorts {
+			img.ExposedPorts = append(img.ExposedPorts, nat.Port(port))
 		}
-		defer rdr.Close()
-		data, err := io.ReadAll(rdr)
+		sort.Slice(img.ExposedPorts, func(a, b int) bool {
+			return img.ExposedPorts[a] < img.ExposedPorts[b]
+		})
+	}
+	return img
+}
+
+// Used to convert an image listed by the daemon, which only has a summary of
+// its metadata. The image is named by its first tag, or its ID when untagged.
+func summaryImage(summary image.Summary) *Image {
+	img := &Image{
+		Name:    summary.ID,
+		ID:      summary.ID,
+		Tags:    summary.RepoTags,
+		Digests: summary.RepoDigests,
+		Labels:  summary.Labels,
+		Size:    summary.Size,
+		Created: time.Unix(summary.Created, 0),
+	}
+	if len(summary.RepoTags) > 0 {
+		img.Name = summary.RepoTags[0]
+	}
+	if len(summary.RepoDigests) > 0 {
+		img.Digest = summary.RepoDigests[0]
+	}
+	return img
+}
+
+// The modification time given to every file in a build context, so that the
+// same files always produce the same context.
+var buildContextModTime = time.Unix(0, 0)
+
+// Used to write the files under the specified path to a tar archive, skipping
+// those matched by excludes and those for which skip returns true, either of
+// which may be nil. File modes, symlinks and empty
+// directories are preserved, while ownership and modification times are
+// normalised so that the archive only depends on the files' content.
+func loadBuildContext(path string, tw *tar.Writer, excludes *patternmatcher.PatternMatcher, skip func(name string) bool) error {
+	return filepath.WalkDir(path, func(filename string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("failed to read %s: %v", filename, err)
 		}
+		rel, err := filepath.Rel(path, filename)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
 		// Use ToSlash to make the filepath generic. This solves the issue where
 		// Windows uses backslashes and Docker uses forward slashs.
-		name := filepath.ToSlash(filepath.Join(relativePath, entry.Name()))
-		header := &tar.Header{
-			Name: name,
-			Size: int64(len(data)),
+		name := filepath.ToSlash(rel)
+		if skip != nil && skip(name) {
+			return nil
+		}
+		excluded, err := isExcluded(excludes, name)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			// An excluded directory still needs to be walked when a later
+			// pattern, such as "!dir/keep", could include some of its files.
+			if entry.IsDir() && !excludes.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %v", filename, err)
+		}
+		header, err := tarHeader(filename, name, info)
+		if err != nil {
+			return err
+		}
+		normaliseBuildContextHeader(header)
+		return writeTarHeader(tw, filename, header)
+	})
+}
+
+// Used to write the files of fsys to a tar archive, skipping those matched by
+// excludes and those for which skip returns true, either of which may be nil.
+// Entries are normalised in the same way as loadBuildContext. Symlinks are not
+// supported by fs.FS, so are skipped.
+func loadBuildContextFS(fsys fs.FS, tw *tar.Writer, excludes *patternmatcher.PatternMatcher, skip func(name string) bool) error {
+	return fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
 		}
+		if name == "." || skip != nil && skip(name) {
+			return nil
+		}
+		excluded, err := isExcluded(excludes, name)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			if entry.IsDir() && !excludes.Exclusions() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %v", name, err)
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("failed to create tar header for %s: %v", name, err)
+		}
+		header.Name = name
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+		normaliseBuildContextHeader(header)
 		err = tw.WriteHeader(header)
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(name)
 		if err != nil {
-			return fmt.Errorf("failed to write tar header for %s: %v", name, err)
+			return fmt.Errorf("failed to open %s: %v", name, err)
 		}
-		_, err = tw.Write(data)
+		defer f.Close()
+		_, err = io.Copy(tw, f)
 		if err != nil {
 			return fmt.Errorf("failed to write tar body for %s: %v", name, err)
 		}
+		return nil
+	})
+}
+
+// Used to get the path of the Dockerfile within the build context.
+func buildContextDockerfile(opt *options.BuildImageOptions) string {
+	return path.Clean(filepath.ToSlash(opt.Dockerfile()))
+}
+
+// Used to write a single in-memory file to a build context.
+func writeBuildContextFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+	}
+	normaliseBuildContextHeader(header)
+	err := tw.WriteHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %v", name, err)
+	}
+	_, err = tw.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write tar body for %s: %v", name, err)
 	}
 	return nil
 }
 
-func logBuildOutput(r io.Reader, name string) error {
-	data, err := io.ReadAll(r)
+// Used to remove ownership and timestamps from a build context entry, so that
+// the context only depends on the content and modes of its files.
+func normaliseBuildContextHeader(header *tar.Header) {
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	header.ModTime = buildContextModTime
+	header.AccessTime, header.ChangeTime = time.Time{}, time.Time{}
+	header.Format = tar.FormatPAX
+}
+
+func isExcluded(excludes *patternmatcher.PatternMatcher, name string) (bool, error) {
+	if excludes == nil {
+		return false, nil
+	}
+	excluded, err := excludes.MatchesOrParentMatches(name)
 	if err != nil {
-		return fmt.Errorf("failed to read image build output: %v", err)
+		return false, fmt.Errorf("failed to match %s against build context exclusions: %v", name, err)
 	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		fmt.Printf("[%s]: %s\n", name, line)
-		var errorData map[string]interface{}
-		err = json.Unmarshal([]byte(line), &errorData)
-		if err == nil {
-			message, ok := errorData["error"]
-			if ok {
-				fmt.Printf("[%s]: failed to build image: %s\n", name, message)
-				return fmt.Errorf("failed to build image: %s", message)
+	return excluded, nil
+}
+
+// The number of build output lines included in a BuildError.
+const buildErrorLogTail = 20
+
+var buildStepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+
+// Used to decode the build output stream as it is received, writing each line
+// to the output and reporting progress events. The ID of the built image is
+// returned, if the daemon reported it.
+func (i ImageOperations) readBuildOutput(r io.Reader, name string, onProgress progress.BuildFunc) (string, error) {
+	state := progress.BuildEvent{}
+	tail := make([]string, 0, buildErrorLogTail)
+	step := ""
+	decoder := json.NewDecoder(r)
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read image build output: %v", err)
+		}
+		errorMessage := message.ErrorMessage
+		if message.Error != nil {
+			errorMessage = message.Error.Message
+		}
+		if errorMessage != "" {
+			i.logger.Error("Failed to build image", "image", name, "phase", "build", "step", step, "error", errorMessage)
+			return "", &BuildError{
+				Image:   name,
+				Step:    step,
+				Message: errorMessage,
+				LogTail: tail,
+			}
+		}
+		if message.Aux != nil {
+			var aux struct {
+				ID string `json:"ID"`
+			}
+			if json.Unmarshal(*message.Aux, &aux) == nil && aux.ID != "" {
+				state.ImageID = aux.ID
+				state.Message = ""
+				if onProgress != nil {
+					onProgress(state)
+				}
+			}
+			continue
+		}
+		for _, line := range strings.Split(message.Stream, "\n") {
+			text := strings.TrimSpace(line)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(i.output, "[%s]: %s\n", name, text)
+			if match := buildStepPattern.FindStringSubmatch(text); match != nil {
+				step = text
+				state.Step, _ = strconv.Atoi(match[1])
+				state.TotalSteps, _ = strconv.Atoi(match[2])
+				state.Instruction = match[3]
+				state.CacheHit = false
+			}
+			if text == "---> Using cache" {
+				state.CacheHit = true
+			}
+			if len(tail) == buildErrorLogTail {
+				tail = tail[1:]
+			}
+			tail = append(tail, text)
+			state.Message = text
+			if onProgress != nil {
+				onProgress(state)
 			}
 		}
 	}
-	return nil
+	i.logger.Debug("Built image", "image", name, "image_id", state.ImageID, "phase", "build")
+	return state.ImageID, nil
 }
@@ -0,0 +1,505 @@
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/dockerfile"
+	"github.com/james226/dockerclient/options"
+	"github.com/james226/dockerclient/progress"
+)
+
+func TestReadBuildOutput_GivenBuildStream_ReportsProgressAndImageID(t *testing.T) {
+	output := strings.Join([]string{
+		`{"stream":"Step 1/2 : FROM alpine"}`,
+		`{"stream":"\n"}`,
+		`{"stream":" ---> 1d34ffeaf190\n"}`,
+		`{"stream":"Step 2/2 : RUN apk add curl\n ---> Using cache\n"}`,
+		`{"aux":{"ID":"sha256:5b2c"}}`,
+		`{"stream":"Successfully built 5b2c\n"}`,
+	}, "\n")
+	out := new(bytes.Buffer)
+	ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: out}
+	events := make([]progress.BuildEvent, 0)
+
+	id, err := ops.readBuildOutput(strings.NewReader(output), "app", func(event progress.BuildEvent) {
+		events = append(events, event)
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:5b2c", id)
+	assert.Equal(t, progress.BuildEvent{Step: 1, TotalSteps: 2, Instruction: "FROM alpine", Message: "Step 1/2 : FROM alpine"}, events[0])
+	assert.Equal(t, progress.BuildEvent{Step: 2, TotalSteps: 2, Instruction: "RUN apk add curl", Message: "---> Using cache", CacheHit: true}, events[3])
+	assert.Equal(t, "sha256:5b2c", events[4].ImageID)
+	assert.Contains(t, out.String(), "[app]: Successfully built 5b2c\n")
+}
+
+func TestReadBuildOutput_GivenInvalidStream_ReturnsError(t *testing.T) {
+	ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: io.Discard}
+
+	_, err := ops.readBuildOutput(strings.NewReader("{not json"), "app", nil)
+	assert.ErrorContains(t, err, "failed to read image build output")
+}
+
+func TestLoadBuildContext_GivenDirectory_PreservesMetadataDeterministically(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "entrypoint.sh"), []byte("#!/bin/sh"), 0o755))
+	assert.Nil(t, os.Symlink("entrypoint.sh", filepath.Join(dir, "start")))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "empty"), 0o700))
+
+	content := streamTar(func(tw *tar.Writer) error {
+		return loadBuildContext(dir, tw, nil, nil)
+	})
+	defer content.Close()
+	headers := map[string]*tar.Header{}
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		headers[header.Name] = header
+	}
+
+	assert.Len(t, headers, 3)
+	assert.Equal(t, int64(0o755), headers["entrypoint.sh"].Mode&0o777)
+	assert.Equal(t, byte(tar.TypeSymlink), headers["start"].Typeflag)
+	assert.Equal(t, "entrypoint.sh", headers["start"].Linkname)
+	assert.Equal(t, byte(tar.TypeDir), headers["empty/"].Typeflag)
+	for _, header := range headers {
+		assert.True(t, buildContextModTime.Equal(header.ModTime), header.Name)
+		assert.Equal(t, 0, header.Uid)
+	}
+}
+
+func TestLoadBuildContextFS_GivenFSWithDockerignore_ExcludesIgnoredFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		".dockerignore":    {Data: []byte("**/*.log\n")},
+		"Dockerfile":       {Data: []byte("FROM old")},
+		"app/main.go":      {Data: []byte("package main"), Mode: 0o600},
+		"app/debug.log":    {Data: []byte("debug")},
+		"build/output.log": {Data: []byte("output")},
+	}
+	excludes, err := buildContextMatcher(fsys, "Dockerfile", []string{"build"})
+	assert.Nil(t, err)
+
+	content := streamTar(func(tw *tar.Writer) error {
+		err := writeBuildContextFile(tw, "Dockerfile", []byte("FROM alpine"))
+		if err != nil {
+			return err
+		}
+		return loadBuildContextFS(fsys, tw, excludes, func(name string) bool {
+			return name == "Dockerfile"
+		})
+	})
+	defer content.Close()
+	files := map[string]string{}
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		body, err := io.ReadAll(tr)
+		assert.Nil(t, err)
+		files[header.Name] = string(body)
+		assert.True(t, buildContextModTime.Equal(header.ModTime), header.Name)
+	}
+
+	assert.Equal(t, map[string]string{
+		".dockerignore": "**/*.log\n",
+		"Dockerfile":    "FROM alpine",
+		"app/":          "",
+		"app/main.go":   "package main",
+	}, files)
+}
+
+func TestPullAuth_GivenExplicitCredentials_EncodesRegistryAuth(t *testing.T) {
+	opt := options.WithRegistryCredentials("user", "secret").
+		WithDockerConfig(filepath.Join(t.TempDir(), "config.json"))
+
+	header, err := pullAuth(context.Background(), "registry.example.com/app:1.0", opt)
+
+	assert.Nil(t, err)
+	decoded, err := base64.URLEncoding.DecodeString(header)
+	assert.Nil(t, err)
+	var creds registry.AuthConfig
+	assert.Nil(t, json.Unmarshal(decoded, &creds))
+	assert.Equal(t, registry.AuthConfig{Username: "user", Password: "secret", ServerAddress: "registry.example.com"}, creds)
+}
+
+func TestPullAuth_GivenNoCredentials_ReturnsEmptyHeader(t *testing.T) {
+	opt := options.PullImage().WithDockerConfig(filepath.Join(t.TempDir(), "config.json"))
+
+	header, err := pullAuth(context.Background(), "alpine", opt)
+
+	assert.Nil(t, err)
+	assert.Empty(t, header)
+}
+
+func TestReadPullOutput_GivenPullStream_ReportsLayerProgress(t *testing.T) {
+	output := strings.Join([]string{
+		`{"status":"Pulling from library/alpine","id":"3.20"}`,
+		`{"status":"Pulling fs layer","progressDetail":{},"id":"a3ed95caeb02"}`,
+		`{"status":"Downloading","progressDetail":{"current":1024,"total":4096},"progress":"[=>  ]","id":"a3ed95caeb02"}`,
+		`{"status":"Pull complete","progressDetail":{},"id":"a3ed95caeb02"}`,
+		`{"status":"Digest: sha256:9f1c"}`,
+		`{"status":"Status: Downloaded newer image for alpine:3.20"}`,
+	}, "\n")
+	out := new(bytes.Buffer)
+	ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: out}
+	events := make([]progress.PullEvent, 0)
+
+	digest, err := ops.readPullOutput(strings.NewReader(output), "alpine:3.20", options.WithPullProgress(func(event progress.PullEvent) {
+		events = append(events, event)
+	}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:9f1c", digest)
+	assert.Len(t, events, 6)
+	assert.Equal(t, progress.PullEvent{LayerID: "a3ed95caeb02", Status: "Downloading", Current: 1024, Total: 4096}, events[2])
+	assert.Contains(t, out.String(), "[alpine:3.20]: a3ed95caeb02: Pull complete\n")
+	assert.NotContains(t, out.String(), "Downloading")
+	assert.Equal(t, "alpine@sha256:9f1c", digestReference("alpine:3.20", digest))
+}
+
+func TestReadPullOutput_GivenErrorDetail_ReturnsError(t *testing.T) {
+	output := `{"status":"Pulling from app","id":"1.0"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`
+	ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: io.Discard}
+
+	_, err := ops.readPullOutput(strings.NewReader(output), "app:1.0", options.PullImage())
+
+	assert.EqualError(t, err, "manifest unknown")
+}
+
+func TestReadPullOutput_WhenQuiet_WritesNoOutput(t *testing.T) {
+	out := new(bytes.Buffer)
+	ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: out}
+
+	_, err := ops.readPullOutput(strings.NewReader(`{"status":"Pulling fs layer","id":"a3ed95caeb02"}`), "app", options.PullImage().WithQuiet())
+
+	assert.Nil(t, err)
+	assert.Empty(t, out.String())
+}
+
+func TestPull_WhenContextCancelled_ReturnsPromptly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"Pulling fs layer","id":"a3ed95caeb02"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := c.Images.Pull(ctx, "alpine", options.WithPullProgress(func(event progress.PullEvent) {
+		cancel()
+	}).WithDockerConfig(filepath.Join(t.TempDir(), "config.json")))
+
+	var pullError *PullError
+	assert.ErrorAs(t, err, &pullError)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// Used to create a client for a fake daemon served by server.
+func newTestClient(t *testing.T, server *httptest.Server) *DockerClient {
+	t.Helper()
+	return newTestClientWithOptions(t, server, options.Client())
+}
+
+// Used to create a client for the fake daemon with additional client options.
+func newTestClientWithOptions(t *testing.T, server *httptest.Server, opt *options.ClientOptions) *DockerClient {
+	t.Helper()
+	c, err := NewClient(opt.WithHost("tcp://" + server.Listener.Addr().String()).
+		WithAPIVersion("1.45").
+		Quiet())
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func TestEnsure_GivenPolicy_PullsOnlyWhenRequired(t *testing.T) {
+	tests := map[string]struct {
+		policy  options.PullPolicy
+		present bool
+		pulled  bool
+		err     error
+	}{
+		"if not present when present": {policy: options.PullIfNotPresent, present: true},
+		"if not present when missing": {policy: options.PullIfNotPresent, pulled: true},
+		"always when present":         {policy: options.PullAlways, present: true, pulled: true},
+		"never when present":          {policy: options.PullNever, present: true},
+		"never when missing":          {policy: options.PullNever, err: ErrNotFound},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pulled := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/images/create"):
+					pulled = true
+					w.Write([]byte(`{"status":"Digest: sha256:9f1c"}`))
+				case test.present:
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"Id":"sha256:5b2c","RepoDigests":["alpine@sha256:9f1c"]}`))
+				default:
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"message":"No such image: alpine:3.20"}`))
+				}
+			}))
+			defer server.Close()
+			c := newTestClient(t, server)
+
+			img, err := c.Images.Ensure(context.Background(), "alpine:3.20", test.policy,
+				options.PullImage().WithDockerConfig(filepath.Join(t.TempDir(), "config.json")))
+
+			assert.Equal(t, test.pulled, pulled)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "alpine@sha256:9f1c", img.Digest)
+		})
+	}
+}
+
+func TestGet_GivenImage_ReturnsMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"Id": "sha256:5b2c",
+			"RepoTags": ["app:1.0", "app:latest"],
+			"RepoDigests": ["app@sha256:9f1c"],
+			"Created": "2024-05-01T10:00:00.5Z",
+			"Architecture": "arm64",
+			"Os": "linux",
+			"Size": 4096,
+			"Config": {
+				"Env": ["PATH=/usr/bin"],
+				"Entrypoint": ["/app"],
+				"Cmd": ["serve"],
+				"ExposedPorts": {"8080/tcp": {}, "53/udp": {}},
+				"Labels": {"team": "core"}
+			}
+		}`))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	img, err := c.Images.Get(context.Background(), "app:1.0")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:5b2c", img.ID)
+	assert.Equal(t, "app@sha256:9f1c", img.Digest)
+	assert.Equal(t, []string{"app:1.0", "app:latest"}, img.Tags)
+	assert.Equal(t, []string{"PATH=/usr/bin"}, img.Env)
+	assert.Equal(t, []string{"/app"}, img.Entrypoint)
+	assert.Equal(t, []nat.Port{"53/udp", "8080/tcp"}, img.ExposedPorts)
+	assert.Equal(t, map[string]string{"team": "core"}, img.Labels)
+	assert.Equal(t, int64(4096), img.Size)
+	assert.Equal(t, "arm64", img.Architecture)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 5e8, time.UTC), img.Created)
+}
+
+func TestList_GivenFilters_SendsFiltersAndReturnsImages(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("filters")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"Id": "sha256:5b2c", "RepoTags": ["app:1.0"], "Created": 1714557600, "Size": 4096},
+			{"Id": "sha256:7d1e", "RepoTags": [], "Created": 1714557600, "Size": 1024}
+		]`))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	images, err := c.Images.List(context.Background(), options.WithReference("app").WithLabel("team", "core"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, query, `"reference":{"app":true}`)
+	assert.Contains(t, query, `"label":{"team=core":true}`)
+	assert.Len(t, images, 2)
+	assert.Equal(t, "app:1.0", images[0].Name)
+	assert.Equal(t, "sha256:7d1e", images[1].Name)
+}
+
+func TestPrune_GivenFilters_ReturnsReport(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("filters")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ImagesDeleted": [{"Untagged": "app:old"}, {"Deleted": "sha256:5b2c"}], "SpaceReclaimed": 4096}`))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	report, err := c.Images.Prune(context.Background(), false, 24*time.Hour, map[string]string{"team": "core"})
+
+	assert.Nil(t, err)
+	assert.Contains(t, query, `"dangling":{"false":true}`)
+	assert.Contains(t, query, `"until":{"24h0m0s":true}`)
+	assert.Equal(t, &ImagePruneReport{
+		ImagesDeleted:  []string{"sha256:5b2c"},
+		ImagesUntagged: []string{"app:old"},
+		SpaceReclaimed: 4096,
+	}, report)
+}
+
+func TestPush_GivenNewReference_TagsAndPushesImage(t *testing.T) {
+	var tagQuery, registryAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tag"):
+			tagQuery = r.URL.RawQuery
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasSuffix(r.URL.Path, "/push"):
+			registryAuth = r.Header.Get("X-Registry-Auth")
+			w.Write([]byte(strings.Join([]string{
+				`{"status":"The push refers to repository [localhost:5000/app]"}`,
+				`{"status":"Pushing","progressDetail":{"current":512,"total":1024},"id":"a3ed95caeb02"}`,
+				`{"status":"Pushed","progressDetail":{},"id":"a3ed95caeb02"}`,
+				`{"status":"1.0: digest: sha256:9f1c size: 528"}`,
+				`{"progressDetail":{},"aux":{"Tag":"1.0","Digest":"sha256:9f1c","Size":528}}`,
+			}, "\n")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+	events := make([]progress.PushEvent, 0)
+
+	pushed, err := c.Images.Push(context.Background(), &Image{Name: "app", ID: "sha256:5b2c"}, "localhost:5000/app:1.0",
+		options.WithPushCredentials("user", "secret").WithProgress(func(event progress.PushEvent) {
+			events = append(events, event)
+		}))
+
+	assert.Nil(t, err)
+	assert.Contains(t, tagQuery, "repo=localhost%3A5000%2Fapp")
+	assert.Contains(t, tagQuery, "tag=1.0")
+	assert.NotEmpty(t, registryAuth)
+	assert.Equal(t, "localhost:5000/app@sha256:9f1c", pushed.Digest)
+	assert.Equal(t, "sha256:5b2c", pushed.ID)
+	assert.Len(t, events, 4)
+	assert.Equal(t, progress.PushEvent{LayerID: "a3ed95caeb02", Status: "Pushing", Current: 512, Total: 1024}, events[1])
+}
+
+func TestPush_GivenErrorDetail_ReturnsPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}`))
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+
+	_, err := c.Images.Push(context.Background(), &Image{Name: "localhost:5000/app:1.0"}, "",
+		options.PushImage().WithDockerConfig(filepath.Join(t.TempDir(), "config.json")))
+
+	var pushError *PushError
+	assert.ErrorAs(t, err, &pushError)
+	assert.Equal(t, "localhost:5000/app:1.0", pushError.Image)
+	assert.ErrorContains(t, err, "authentication required")
+}
+
+func TestEnsure_GivenRewrites_PullsThroughMirror(t *testing.T) {
+	var inspected []string
+	var pulled string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/images/create") {
+			pulled = r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+			w.Write([]byte(`{"status":"Digest: sha256:9f1c"}`))
+			return
+		}
+		inspected = append(inspected, strings.TrimSuffix(r.URL.Path[strings.Index(r.URL.Path, "/images/")+len("/images/"):], "/json"))
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such image"}`))
+	}))
+	defer server.Close()
+	c := newTestClientWithOptions(t, server, options.WithRegistryMirror("docker.io", "mirror.example.com/dockerhub").
+		WithTagRewrite("postgres:16", "16.4"))
+
+	img, err := c.Images.Ensure(context.Background(), "postgres:16", options.PullIfNotPresent,
+		options.PullImage().WithDockerConfig(filepath.Join(t.TempDir(), "config.json")))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"postgres:16", "mirror.example.com/dockerhub/library/postgres:16.4"}, inspected)
+	assert.Equal(t, "mirror.example.com/dockerhub/library/postgres:16.4", pulled)
+	assert.Equal(t, "mirror.example.com/dockerhub/library/postgres:16.4", img.Name)
+}
+
+func TestEnsure_GivenRewritesAndLocalImage_UsesLocalImage(t *testing.T) {
+	var inspected []string
+	pulled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/images/create") {
+			pulled = true
+			return
+		}
+		inspected = append(inspected, strings.TrimSuffix(r.URL.Path[strings.Index(r.URL.Path, "/images/")+len("/images/"):], "/json"))
+		w.Write([]byte(`{"Id":"sha256:9f1c","RepoTags":["postgres:16"]}`))
+	}))
+	defer server.Close()
+	c := newTestClientWithOptions(t, server, options.WithRegistryMirror("docker.io", "mirror.example.com/dockerhub").
+		WithTagRewrite("postgres:16", "16.4"))
+
+	img, err := c.Images.Ensure(context.Background(), "postgres:16", options.PullIfNotPresent)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"postgres:16"}, inspected)
+	assert.False(t, pulled)
+	assert.Equal(t, "postgres:16", img.Name)
+}
+
+func TestBuild_GivenInvalidDockerfileBuilder_ReturnsErrorBeforeCallingDaemon(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	c := newTestClient(t, server)
+	opt := options.BuildImage().WithDockerfileBuilder(dockerfile.New().Run("make").From("alpine"))
+
+	_, err := c.Images.Build(context.Background(), "app", t.TempDir(), opt)
+
+	assert.ErrorContains(t, err, "RUN instruction before the first FROM")
+	assert.False(t, called)
+}
+
+func TestBuildFromFiles_GivenPathOutsideContext_ReturnsErrorNamingFile(t *testing.T) {
+	for _, file := range []string{"../config", "/etc/config", "app/../../config", "."} {
+		ops := ImageOperations{logger: slog.New(slog.DiscardHandler), output: io.Discard}
+
+		_, err := ops.BuildFromFiles(context.Background(), "app", map[string][]byte{
+			"Dockerfile": []byte("FROM alpine\n"),
+			file:         []byte("port: 80"),
+		})
+
+		assert.ErrorContains(t, err, "'"+file+"'")
+	}
+}
@@ -3,7 +3,6 @@ package internal
 import (
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
 	"github.com/docker/docker/pkg/stdcopy"
@@ -47,14 +46,13 @@ func (w *ContainerLogWriter) Write(data []byte) (int, error) {
 	return len(data), nil
 }
 
-// PrintContainerLogs is used to print the output of a container to the stdout/err streams.
-func PrintContainerLogs(name string, out io.Reader) {
-	_, err := stdcopy.StdCopy(
-		NewContainerLogWriter(name, os.Stdout),
-		NewContainerLogWriter(name, os.Stderr),
-		out,
-	)
+// PrintContainerLogs is used to print the stdout and stderr of a container to
+// the given io.Writer, with each line prefixed by the container's name.
+func PrintContainerLogs(name string, out io.Reader, dst io.Writer) error {
+	w := NewContainerLogWriter(name, dst)
+	_, err := stdcopy.StdCopy(w, w, out)
 	if err != nil {
-		fmt.Printf("Failed to print the logs of container '%s'\n", name)
+		return fmt.Errorf("failed to print the logs of container '%s': %v", name, err)
 	}
+	return nil
 }
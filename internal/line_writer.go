@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"bytes"
+)
+
+// LineWriter is an implementation of io.Writer which splits the written data
+// into lines, passing each complete line to a callback. Data following the last
+// newline is held until more data is written, or Flush is called.
+type LineWriter struct {
+	buf    []byte
+	onLine func(line string)
+}
+
+// NewLineWriter is used to return a new instance of LineWriter, which calls
+// onLine with each line written to it, without the trailing newline.
+func NewLineWriter(onLine func(line string)) *LineWriter {
+	return &LineWriter{
+		onLine: onLine,
+	}
+}
+
+// Write is used to buffer the given data, calling the callback for each line
+// completed by it. The returned integer value always matches the length of data.
+func (w *LineWriter) Write(data []byte) (int, error) {
+	w.buf = append(w.buf, data...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(string(bytes.TrimSuffix(w.buf[:i], []byte("\r"))))
+		w.buf = w.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush is used to pass any remaining data, which was not followed by a newline,
+// to the callback.
+func (w *LineWriter) Flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	w.onLine(string(w.buf))
+	w.buf = nil
+}
@@ -0,0 +1,104 @@
+// Package logs provides consumers which receive the output of a container
+// line by line.
+package logs
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Stream identifies which output stream of a container a line was written to.
+type Stream string
+
+const (
+	Stdout Stream = "stdout"
+	Stderr Stream = "stderr"
+)
+
+// Line is a single line of a container's output.
+type Line struct {
+	Stream Stream
+	// Timestamp is only set when timestamps were requested from Docker.
+	Timestamp time.Time
+	Text      string
+}
+
+// String formats the line as it would be printed by "docker logs".
+func (l Line) String() string {
+	if l.Timestamp.IsZero() {
+		return l.Text
+	}
+	return fmt.Sprintf("%s %s", l.Timestamp.Format(time.RFC3339Nano), l.Text)
+}
+
+// Consumer is implemented by types which receive a container's output. Accept
+// may be called from a different goroutine than the one which registered it.
+type Consumer interface {
+	Accept(line Line)
+}
+
+// ConsumerFunc is an adapter to allow the use of a function as a Consumer.
+type ConsumerFunc func(line Line)
+
+// Accept calls f(line).
+func (f ConsumerFunc) Accept(line Line) {
+	f(line)
+}
+
+// WriterConsumer is a Consumer which writes each line to an io.Writer.
+type WriterConsumer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// ToWriter returns a Consumer which writes each line, followed by a newline,
+// to w. Write errors are ignored.
+func ToWriter(w io.Writer) *WriterConsumer {
+	return &WriterConsumer{w: w}
+}
+
+// Accept implements Consumer.
+func (c *WriterConsumer) Accept(line Line) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = fmt.Fprintln(c.w, line.String())
+}
+
+// RingBuffer is a Consumer which retains the most recent lines it has received.
+type RingBuffer struct {
+	mu    sync.Mutex
+	lines []Line
+	next  int
+	full  bool
+}
+
+// NewRingBuffer returns a RingBuffer which retains up to size lines. A size
+// less than 1 retains the most recent line.
+func NewRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{
+		lines: make([]Line, max(size, 1)),
+	}
+}
+
+// Accept implements Consumer.
+func (b *RingBuffer) Accept(line Line) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Lines returns the retained lines, oldest first.
+func (b *RingBuffer) Lines() []Line {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]Line{}, b.lines[:b.next]...)
+	}
+	return append(append([]Line{}, b.lines[b.next:]...), b.lines[:b.next]...)
+}
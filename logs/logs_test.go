@@ -0,0 +1,64 @@
+package logs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineString_WithTimestamp_PrefixesTimestamp(t *testing.T) {
+	line := Line{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Text:      "ready",
+	}
+
+	assert.Equal(t, "2024-01-02T03:04:05Z ready", line.String())
+}
+
+func TestConsumerFunc_WhenAccepting_CallsFunction(t *testing.T) {
+	var received Line
+	consumer := ConsumerFunc(func(line Line) {
+		received = line
+	})
+
+	consumer.Accept(Line{Text: "hello"})
+	assert.Equal(t, "hello", received.Text)
+}
+
+func TestToWriter_WhenAccepting_WritesLines(t *testing.T) {
+	buf := new(bytes.Buffer)
+	consumer := ToWriter(buf)
+
+	consumer.Accept(Line{Text: "first"})
+	consumer.Accept(Line{Text: "second"})
+	assert.Equal(t, "first\nsecond\n", buf.String())
+}
+
+func TestRingBuffer_WhenNotFull_ReturnsAllLines(t *testing.T) {
+	buf := NewRingBuffer(3)
+
+	buf.Accept(Line{Text: "1"})
+	buf.Accept(Line{Text: "2"})
+	assert.Equal(t, []Line{{Text: "1"}, {Text: "2"}}, buf.Lines())
+}
+
+func TestRingBuffer_WhenOverflowing_ReturnsMostRecentLines(t *testing.T) {
+	buf := NewRingBuffer(2)
+
+	buf.Accept(Line{Text: "1"})
+	buf.Accept(Line{Text: "2"})
+	buf.Accept(Line{Text: "3"})
+	assert.Equal(t, []Line{{Text: "2"}, {Text: "3"}}, buf.Lines())
+}
+
+func TestNewRingBuffer_GivenSizeBelowOne_RetainsMostRecentLine(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		buf := NewRingBuffer(size)
+
+		buf.Accept(Line{Text: "1"})
+		buf.Accept(Line{Text: "2"})
+		assert.Equal(t, []Line{{Text: "2"}}, buf.Lines())
+	}
+}
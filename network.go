@@ -29,7 +29,7 @@ func (n NetworkOperations) Create(ctx context.Context, name string) (*Network, e
 		Attachable: true,
 	})
 	if err != nil {
-		panic(err)
+		return nil, dockerError(err)
 	}
 
 	return &Network{ID: newNetwork.ID}, nil
@@ -38,7 +38,7 @@ func (n NetworkOperations) Create(ctx context.Context, name string) (*Network, e
 func getNetwork(ctx context.Context, cli *client.Client, name string) (*Network, error) {
 	networks, err := cli.NetworkList(ctx, network.ListOptions{})
 	if err != nil {
-		return nil, err
+		return nil, dockerError(err)
 	}
 
 	for _, network := range networks {
@@ -0,0 +1,279 @@
+package options
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ClientOptions is used to pass optional arguments when creating a client.
+type ClientOptions struct {
+	logger     *slog.Logger
+	output     io.Writer
+	host       *string
+	tls        *tlsPaths
+	apiVersion *string
+	httpClient *http.Client
+	timeout    *time.Duration
+	headers    map[string]string
+	mirrors    map[string]string
+	tags       map[string]string
+}
+
+type tlsPaths struct {
+	ca   string
+	cert string
+	key  string
+}
+
+// Client returns a new instance of ClientOptions.
+func Client() *ClientOptions {
+	return &ClientOptions{}
+}
+
+// Logger is used to retrieve the logger which the client reports errors and
+// progress to. If no logger is configured, slog.Default() is returned.
+func (opt *ClientOptions) Logger() *slog.Logger {
+	if opt.logger == nil {
+		return slog.Default()
+	}
+	return opt.logger
+}
+
+// Output is used to retrieve the writer which image build, image pull and
+// container output is written to. If no writer is configured, os.Stdout is
+// returned as default.
+func (opt *ClientOptions) Output() io.Writer {
+	if opt.output == nil {
+		return os.Stdout
+	}
+	return opt.output
+}
+
+// DockerOptions is used to retrieve the options used to create the underlying
+// Docker client. The daemon is configured from the environment, the same as the
+// Docker CLI, with any explicitly configured values taking precedence.
+func (opt *ClientOptions) DockerOptions() []client.Opt {
+	opts := []client.Opt{client.FromEnv}
+	if opt.host != nil {
+		opts = append(opts, client.WithHost(*opt.host))
+	}
+	// The HTTP client must be set before TLS, as configuring TLS modifies the
+	// transport of the current HTTP client.
+	if opt.httpClient != nil {
+		opts = append(opts, client.WithHTTPClient(opt.httpClient))
+	}
+	if opt.tls != nil {
+		opts = append(opts, client.WithTLSClientConfig(opt.tls.ca, opt.tls.cert, opt.tls.key))
+	}
+	// The timeout must be set after the host and TLS, as configuring them
+	// replaces the dialer and TLS settings of the transport.
+	if opt.timeout != nil {
+		opts = append(opts, withConnectionTimeout(*opt.timeout))
+	}
+	if len(opt.headers) > 0 {
+		opts = append(opts, client.WithHTTPHeaders(opt.headers))
+	}
+	if opt.apiVersion != nil {
+		opts = append(opts, client.WithVersion(*opt.apiVersion))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+	return opts
+}
+
+// Used to apply a timeout to dialing the daemon, the TLS handshake and waiting
+// for response headers, rather than to whole requests as client.WithTimeout
+// does, which would end streamed responses.
+func withConnectionTimeout(timeout time.Duration) client.Opt {
+	return func(c *client.Client) error {
+		// The HTTP client is a copy, but shares the client's transport.
+		transport, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot apply timeout to transport: %T", c.HTTPClient().Transport)
+		}
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return dial(ctx, network, addr)
+		}
+		transport.TLSHandshakeTimeout = timeout
+		transport.ResponseHeaderTimeout = timeout
+		return nil
+	}
+}
+
+// WithLogger is used to configure the logger which the client reports errors
+// and progress to.
+func (opt *ClientOptions) WithLogger(logger *slog.Logger) *ClientOptions {
+	opt.logger = logger
+	return opt
+}
+
+// WithOutput is used to configure the writer which image build, image pull and
+// container output is written to. Use io.Discard to silence the output.
+func (opt *ClientOptions) WithOutput(w io.Writer) *ClientOptions {
+	opt.output = w
+	return opt
+}
+
+// WithHost is used to configure the address of the Docker daemon, such as
+// "unix:///var/run/docker.sock" or "tcp://10.0.0.2:2376".
+func (opt *ClientOptions) WithHost(host string) *ClientOptions {
+	opt.host = &host
+	return opt
+}
+
+// WithTLS is used to configure the paths of the CA certificate, client certificate
+// and client key used to connect to the Docker daemon over TLS.
+func (opt *ClientOptions) WithTLS(caPath, certPath, keyPath string) *ClientOptions {
+	opt.tls = &tlsPaths{
+		ca:   caPath,
+		cert: certPath,
+		key:  keyPath,
+	}
+	return opt
+}
+
+// WithAPIVersion is used to pin the version of the Docker API, such as "1.45",
+// instead of negotiating it with the daemon.
+func (opt *ClientOptions) WithAPIVersion(version string) *ClientOptions {
+	opt.apiVersion = &version
+	return opt
+}
+
+// WithHTTPClient is used to configure the HTTP client used to connect to the
+// Docker daemon.
+func (opt *ClientOptions) WithHTTPClient(httpClient *http.Client) *ClientOptions {
+	opt.httpClient = httpClient
+	return opt
+}
+
+// WithTimeout is used to configure how long to wait for the Docker daemon to
+// accept a connection, complete a TLS handshake and respond to each request.
+// Once the daemon has responded, reading the response is not limited, so
+// streaming calls such as following container logs, pulling or building images
+// and reading an image's filesystem can run for longer. Use a context deadline
+// to limit individual calls. When an HTTP client is configured, its transport
+// must be an *http.Transport.
+func (opt *ClientOptions) WithTimeout(timeout time.Duration) *ClientOptions {
+	opt.timeout = &timeout
+	return opt
+}
+
+// WithHTTPHeader is used to configure a header sent with each request to the
+// Docker daemon.
+func (opt *ClientOptions) WithHTTPHeader(name, value string) *ClientOptions {
+	if opt.headers == nil {
+		opt.headers = map[string]string{}
+	}
+	opt.headers[name] = value
+	return opt
+}
+
+// WithRegistryMirror is used to pull images from registry through a mirror
+// instead, such as "mirror.example.com/dockerhub" for "docker.io". The mirror
+// is prefixed to the image's repository, so "postgres:16" is pulled as
+// "mirror.example.com/dockerhub/library/postgres:16". Mirrors are used when
+// images are pulled, including when starting containers, while images which
+// already exist locally under their own name are used as they are. Aliases of
+// a registry, such as "index.docker.io", are the same registry, and invalid or
+// duplicate mirrors are reported when the client is created.
+func (opt *ClientOptions) WithRegistryMirror(registry, mirror string) *ClientOptions {
+	if opt.mirrors == nil {
+		opt.mirrors = map[string]string{}
+	}
+	opt.mirrors[registry] = mirror
+	return opt
+}
+
+// WithTagRewrite is used to replace the tag of an image whenever it is pulled,
+// including when starting containers, such as to use "postgres:16.4" in place
+// of "postgres:16". Images which already exist locally under their own name are
+// used as they are. Tags are rewritten before a registry mirror is applied.
+// Images are normalized, so "postgres" and "docker.io/library/postgres:latest"
+// are the same image, and invalid or duplicate rewrites are reported when the
+// client is created.
+func (opt *ClientOptions) WithTagRewrite(image, tag string) *ClientOptions {
+	if opt.tags == nil {
+		opt.tags = map[string]string{}
+	}
+	opt.tags[image] = tag
+	return opt
+}
+
+// WithTagRewrites is used to replace the tags of images, as WithTagRewrite,
+// for each image and tag in the map.
+func (opt *ClientOptions) WithTagRewrites(tags map[string]string) *ClientOptions {
+	for image, tag := range tags {
+		opt.WithTagRewrite(image, tag)
+	}
+	return opt
+}
+
+// RegistryMirrors is used to retrieve the configured mirrors, keyed by the
+// registry which they mirror.
+func (opt *ClientOptions) RegistryMirrors() map[string]string {
+	return opt.mirrors
+}
+
+// TagRewrites is used to retrieve the configured tag rewrites, keyed by the
+// image whose tag is replaced.
+func (opt *ClientOptions) TagRewrites() map[string]string {
+	return opt.tags
+}
+
+// Quiet is used to discard all log messages and output written by the client.
+func (opt *ClientOptions) Quiet() *ClientOptions {
+	return opt.WithLogger(slog.New(slog.DiscardHandler)).WithOutput(io.Discard)
+}
+
+// WithLogger returns a new instance of ClientOptions with the specified logger.
+func WithLogger(logger *slog.Logger) *ClientOptions {
+	return Client().WithLogger(logger)
+}
+
+// WithOutput returns a new instance of ClientOptions with the specified output.
+func WithOutput(w io.Writer) *ClientOptions {
+	return Client().WithOutput(w)
+}
+
+// Quiet returns a new instance of ClientOptions which discards all log messages
+// and output written by the client.
+func Quiet() *ClientOptions {
+	return Client().Quiet()
+}
+
+// WithHost returns a new instance of ClientOptions with the specified daemon address.
+func WithHost(host string) *ClientOptions {
+	return Client().WithHost(host)
+}
+
+// WithAPIVersion returns a new instance of ClientOptions with the specified
+// Docker API version.
+func WithAPIVersion(version string) *ClientOptions {
+	return Client().WithAPIVersion(version)
+}
+
+// WithRegistryMirror returns a new instance of ClientOptions which pulls images
+// from registry through the specified mirror.
+func WithRegistryMirror(registry, mirror string) *ClientOptions {
+	return Client().WithRegistryMirror(registry, mirror)
+}
+
+// WithTagRewrites returns a new instance of ClientOptions with the specified
+// tag rewrites.
+func WithTagRewrites(tags map[string]string) *ClientOptions {
+	return Client().WithTagRewrites(tags)
+}
@@ -0,0 +1,122 @@
+package options
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_WhenCalled_ReturnsDefaultConfig(t *testing.T) {
+	opt := Client()
+
+	assert.Equal(t, slog.Default(), opt.Logger())
+	assert.Equal(t, os.Stdout, opt.Output())
+	assert.Len(t, opt.DockerOptions(), 2)
+}
+
+func TestWithLogger_GivenLogger_SetsLogger(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	opt := WithLogger(logger)
+	assert.Equal(t, logger, opt.Logger())
+}
+
+func TestWithOutput_GivenWriter_SetsOutput(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	opt := WithOutput(buf)
+	assert.Equal(t, buf, opt.Output())
+}
+
+func TestQuiet_WhenCalled_DiscardsOutput(t *testing.T) {
+	opt := Quiet()
+
+	assert.Equal(t, io.Discard, opt.Output())
+	assert.False(t, opt.Logger().Enabled(t.Context(), slog.LevelError))
+}
+
+func TestClientDockerOptions_WhereValuesAreConfigured_CreatesConfiguredClient(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "unix:///from/env.sock")
+	transport := &http.Transport{}
+	opt := WithHost("tcp://10.0.0.2:2375").
+		WithAPIVersion("1.45").
+		WithHTTPClient(&http.Client{Transport: transport}).
+		WithTimeout(time.Minute).
+		WithHTTPHeader("X-Test", "true")
+
+	cli, err := client.NewClientWithOpts(opt.DockerOptions()...)
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	assert.Equal(t, "tcp://10.0.0.2:2375", cli.DaemonHost())
+	assert.Equal(t, "1.45", cli.ClientVersion())
+	assert.Zero(t, cli.HTTPClient().Timeout)
+	assert.Equal(t, time.Minute, transport.ResponseHeaderTimeout)
+	assert.Equal(t, time.Minute, transport.TLSHandshakeTimeout)
+}
+
+func TestClientWithTimeout_GivenStreamedResponse_DoesNotEndStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"status":"done"}`))
+	}))
+	defer server.Close()
+	cli, err := client.NewClientWithOpts(WithHost("tcp://" + server.Listener.Addr().String()).
+		WithAPIVersion("1.45").
+		WithTimeout(50 * time.Millisecond).
+		DockerOptions()...)
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	reader, err := cli.ImagePull(t.Context(), "alpine", image.PullOptions{})
+	assert.Nil(t, err)
+	defer reader.Close()
+	body, err := io.ReadAll(reader)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"status":"done"}`, string(body))
+}
+
+func TestClientWithTimeout_GivenSlowResponseHeaders_ReturnsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	cli, err := client.NewClientWithOpts(WithHost("tcp://" + server.Listener.Addr().String()).
+		WithAPIVersion("1.45").
+		WithTimeout(50 * time.Millisecond).
+		DockerOptions()...)
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	_, err = cli.Ping(t.Context())
+
+	assert.ErrorContains(t, err, "timeout awaiting response headers")
+}
+
+func TestClientWithTLS_GivenMissingCertificates_ReturnsError(t *testing.T) {
+	opt := Client().WithTLS("ca.pem", "cert.pem", "key.pem")
+
+	_, err := client.NewClientWithOpts(opt.DockerOptions()...)
+	assert.NotNil(t, err)
+}
+
+func TestWithRegistryMirror_GivenMirrorAndTags_SetsRewrites(t *testing.T) {
+	opt := WithRegistryMirror("docker.io", "mirror.example.com/dockerhub").
+		WithTagRewrites(map[string]string{"postgres:16": "16.4"}).
+		WithTagRewrite("redis", "7-alpine")
+
+	assert.Equal(t, map[string]string{"docker.io": "mirror.example.com/dockerhub"}, opt.RegistryMirrors())
+	assert.Equal(t, map[string]string{"postgres:16": "16.4", "redis": "7-alpine"}, opt.TagRewrites())
+}
@@ -2,17 +2,28 @@ package options
 
 import (
 	"fmt"
+	"path/filepath"
 
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/go-connections/nat"
+
+	"github.com/james226/dockerclient/logs"
+	"github.com/james226/dockerclient/wait"
 )
 
 // StartContainerOptions is used to pass optional arguments when starting a container.
 type StartContainerOptions struct {
 	name        *string
 	ports       map[uint16]string
+	randomPorts []string
 	environment map[string]string
 	platform    *string
 	capAdd      []string
+	waitFor     wait.Strategy
+	mounts      []mount.Mount
+	consumers   []logs.Consumer
+	pullPolicy  *PullPolicy
+	pullOptions *PullImageOptions
 }
 
 // StartContainer returns a new instance of StartContainerOptions.
@@ -39,6 +50,8 @@ func (opt *StartContainerOptions) Ports() (nat.PortSet, map[nat.Port][]nat.PortB
 	for host, container := range opt.ports {
 		ports = append(ports, fmt.Sprintf("%d:%s", host, container))
 	}
+	// Omitting the host port lets Docker allocate an ephemeral one.
+	ports = append(ports, opt.randomPorts...)
 	return nat.ParsePortSpecs(ports)
 }
 
@@ -69,18 +82,88 @@ func (opt *StartContainerOptions) CapAdd() []string {
 	return opt.capAdd
 }
 
+// Mounts is used to retrieve the configured volume, bind and tmpfs mounts. Bind
+// mount sources are resolved to absolute paths, as required by Docker, and an
+// error value is returned if a source cannot be resolved.
+func (opt *StartContainerOptions) Mounts() ([]mount.Mount, error) {
+	mounts := make([]mount.Mount, 0, len(opt.mounts))
+	for _, m := range opt.mounts {
+		if m.Type == mount.TypeBind {
+			source, err := filepath.Abs(m.Source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve bind mount source '%s': %v", m.Source, err)
+			}
+			m.Source = source
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// LogConsumers returns the consumers which the container's output is streamed to
+// once it has started.
+func (opt *StartContainerOptions) LogConsumers() []logs.Consumer {
+	return opt.consumers
+}
+
+// WaitStrategy returns the strategy used to wait for the container to become
+// ready. If no strategy is configured, nil followed by a false value is returned.
+func (opt *StartContainerOptions) WaitStrategy() (wait.Strategy, bool) {
+	if opt.waitFor == nil {
+		return nil, false
+	}
+	return opt.waitFor, true
+}
+
+// PullPolicy returns the policy used to ensure the image exists before the
+// container is created. If no policy is configured, the image is not checked
+// and a false value is returned.
+func (opt *StartContainerOptions) PullPolicy() (PullPolicy, bool) {
+	if opt.pullPolicy == nil {
+		return PullIfNotPresent, false
+	}
+	return *opt.pullPolicy, true
+}
+
+// PullOptions returns the options used when the image is pulled before the
+// container is created.
+func (opt *StartContainerOptions) PullOptions() *PullImageOptions {
+	if opt.pullOptions == nil {
+		return PullImage()
+	}
+	return opt.pullOptions
+}
+
 // WithName is used to configure the name of the container to start.
 func (opt *StartContainerOptions) WithName(name string) *StartContainerOptions {
 	opt.name = &name
 	return opt
 }
 
-// WithPortBinding is used to configure a port binding to the container.
+// WithPortBinding is used to configure a port binding to the container. A host
+// port of 0 binds the container port to an ephemeral host port, which can be
+// looked up once the container has started.
 func (opt *StartContainerOptions) WithPortBinding(host, container uint16, protocol string) *StartContainerOptions {
+	if host == 0 {
+		return opt.WithRandomPortBinding(container, protocol)
+	}
 	opt.ports[host] = fmt.Sprintf("%d/%s", container, protocol)
 	return opt
 }
 
+// WithRandomPortBinding is used to bind a container port to an ephemeral port on
+// the host, chosen by Docker when the container is started.
+func (opt *StartContainerOptions) WithRandomPortBinding(container uint16, protocol string) *StartContainerOptions {
+	opt.randomPorts = append(opt.randomPorts, fmt.Sprintf("%d/%s", container, protocol))
+	return opt
+}
+
+// ExposeRandom is used to expose a TCP port on the container, bound to an
+// ephemeral port on the host.
+func (opt *StartContainerOptions) ExposeRandom(port uint16) *StartContainerOptions {
+	return opt.WithRandomPortBinding(port, "tcp")
+}
+
 // Expose is used to expose a TCP port on the container. The specified port will
 // be exposed on the container and bound to the same port on the host.
 func (opt *StartContainerOptions) Expose(port uint16) *StartContainerOptions {
@@ -114,16 +197,108 @@ func (opt *StartContainerOptions) WithCapAdd(c ...string) *StartContainerOptions
 	return opt
 }
 
+// WithWaitStrategy is used to configure a strategy which must pass before the
+// container is considered started.
+func (opt *StartContainerOptions) WithWaitStrategy(strategy wait.Strategy) *StartContainerOptions {
+	opt.waitFor = strategy
+	return opt
+}
+
+// WithVolumeMount is used to mount a named volume into the container at the
+// target path. The volume is created by Docker if it does not already exist.
+func (opt *StartContainerOptions) WithVolumeMount(volume, target string, readOnly bool) *StartContainerOptions {
+	opt.mounts = append(opt.mounts, mount.Mount{
+		Type:     mount.TypeVolume,
+		Source:   volume,
+		Target:   target,
+		ReadOnly: readOnly,
+	})
+	return opt
+}
+
+// WithBindMount is used to mount a file or directory from the host into the
+// container at the target path. Relative source paths are resolved against the
+// current working directory.
+func (opt *StartContainerOptions) WithBindMount(source, target string, readOnly bool) *StartContainerOptions {
+	opt.mounts = append(opt.mounts, mount.Mount{
+		Type:     mount.TypeBind,
+		Source:   source,
+		Target:   target,
+		ReadOnly: readOnly,
+	})
+	return opt
+}
+
+// WithBindMountPropagation is used to mount a file or directory from the host
+// into the container, with the given mount propagation mode.
+func (opt *StartContainerOptions) WithBindMountPropagation(source, target string, readOnly bool, propagation mount.Propagation) *StartContainerOptions {
+	opt.mounts = append(opt.mounts, mount.Mount{
+		Type:     mount.TypeBind,
+		Source:   source,
+		Target:   target,
+		ReadOnly: readOnly,
+		BindOptions: &mount.BindOptions{
+			Propagation: propagation,
+		},
+	})
+	return opt
+}
+
+// WithTmpfsMount is used to mount an in-memory filesystem into the container at
+// the target path. A size of 0 leaves the size of the filesystem unlimited.
+func (opt *StartContainerOptions) WithTmpfsMount(target string, sizeBytes int64) *StartContainerOptions {
+	opt.mounts = append(opt.mounts, mount.Mount{
+		Type:   mount.TypeTmpfs,
+		Target: target,
+		TmpfsOptions: &mount.TmpfsOptions{
+			SizeBytes: sizeBytes,
+		},
+	})
+	return opt
+}
+
+// WithLogConsumer is used to stream the container's output to the given consumers,
+// from when it is started until it stops.
+func (opt *StartContainerOptions) WithLogConsumer(consumers ...logs.Consumer) *StartContainerOptions {
+	opt.consumers = append(opt.consumers, consumers...)
+	return opt
+}
+
+// WithPullPolicy is used to ensure the image exists, according to the given
+// policy, before the container is created. The pull options, such as registry
+// credentials, are used when the image is pulled.
+func (opt *StartContainerOptions) WithPullPolicy(policy PullPolicy, opts ...*PullImageOptions) *StartContainerOptions {
+	opt.pullPolicy = &policy
+	if len(opts) > 0 {
+		opt.pullOptions = opts[0]
+	}
+	return opt
+}
+
 // WithName is used to configure the name of the container to start.
 func WithName(name string) *StartContainerOptions {
 	return StartContainer().WithName(name)
 }
 
-// WithPortBinding is used to configure a port binding to the container.
+// WithPortBinding is used to configure a port binding to the container. A host
+// port of 0 binds the container port to an ephemeral host port, which can be
+// looked up once the container has started.
 func WithPortBinding(host, container uint16, protocol string) *StartContainerOptions {
 	return StartContainer().WithPortBinding(host, container, protocol)
 }
 
+// WithRandomPortBinding is used to bind a container port to an ephemeral port on
+// the host, chosen by Docker when the container is started.
+func WithRandomPortBinding(container uint16, protocol string) *StartContainerOptions {
+	return StartContainer().WithRandomPortBinding(container, protocol)
+}
+
+// ExposeRandom is used to expose a TCP port on the container, bound to an
+// ephemeral port on the host.
+func ExposeRandom(port uint16) *StartContainerOptions {
+	return StartContainer().ExposeRandom(port)
+}
+
 // Expose is used to expose a TCP port on the container. The specified port will
 // be exposed on the container and bound to the same port on the host.
 func Expose(port uint16) *StartContainerOptions {
@@ -144,3 +319,40 @@ func WithEnvironmentVariables(values map[string]string) *StartContainerOptions {
 func WithCapAdd(c ...string) *StartContainerOptions {
 	return StartContainer().WithCapAdd(c...)
 }
+
+// WithWaitStrategy is used to configure a strategy which must pass before the
+// container is considered started.
+func WithWaitStrategy(strategy wait.Strategy) *StartContainerOptions {
+	return StartContainer().WithWaitStrategy(strategy)
+}
+
+// WithVolumeMount is used to mount a named volume into the container at the
+// target path. The volume is created by Docker if it does not already exist.
+func WithVolumeMount(volume, target string, readOnly bool) *StartContainerOptions {
+	return StartContainer().WithVolumeMount(volume, target, readOnly)
+}
+
+// WithBindMount is used to mount a file or directory from the host into the
+// container at the target path. Relative source paths are resolved against the
+// current working directory.
+func WithBindMount(source, target string, readOnly bool) *StartContainerOptions {
+	return StartContainer().WithBindMount(source, target, readOnly)
+}
+
+// WithTmpfsMount is used to mount an in-memory filesystem into the container at
+// the target path. A size of 0 leaves the size of the filesystem unlimited.
+func WithTmpfsMount(target string, sizeBytes int64) *StartContainerOptions {
+	return StartContainer().WithTmpfsMount(target, sizeBytes)
+}
+
+// WithLogConsumer is used to stream the container's output to the given consumers,
+// from when it is started until it stops.
+func WithLogConsumer(consumers ...logs.Consumer) *StartContainerOptions {
+	return StartContainer().WithLogConsumer(consumers...)
+}
+
+// WithPullPolicy is used to ensure the image exists, according to the given
+// policy, before the container is created.
+func WithPullPolicy(policy PullPolicy, opts ...*PullImageOptions) *StartContainerOptions {
+	return StartContainer().WithPullPolicy(policy, opts...)
+}
@@ -2,9 +2,15 @@ package options
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types/mount"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/logs"
+	"github.com/james226/dockerclient/wait"
 )
 
 func TestStartContainer_WhenCalled_ReturnsNewInstanceWithDefaultValues(t *testing.T) {
@@ -29,6 +35,11 @@ func TestStartContainer_WhenCalled_ReturnsNewInstanceWithDefaultValues(t *testin
 	v, ok = opt.Platform()
 	assert.Empty(t, v)
 	assert.False(t, ok)
+
+	// Wait Strategy
+	strategy, ok := opt.WaitStrategy()
+	assert.Nil(t, strategy)
+	assert.False(t, ok)
 }
 
 func TestWithName_GivenName_SetsName(t *testing.T) {
@@ -49,6 +60,23 @@ func TestWithPortBinding_GivenValues_SetsPortBinding(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("%d/%s", container, proto), v)
 }
 
+func TestWithPortBinding_GivenZeroHostPort_SetsRandomPortBinding(t *testing.T) {
+	opt := WithPortBinding(0, 8080, "tcp").WithPortBinding(0, 8081, "tcp")
+
+	assert.Len(t, opt.ports, 0)
+	assert.Equal(t, []string{"8080/tcp", "8081/tcp"}, opt.randomPorts)
+}
+
+func TestWithRandomPortBinding_GivenValues_SetsRandomPortBinding(t *testing.T) {
+	opt := WithRandomPortBinding(53, "udp")
+	assert.Equal(t, []string{"53/udp"}, opt.randomPorts)
+}
+
+func TestExposeRandom_GivenPort_SetsRandomTcpBinding(t *testing.T) {
+	opt := ExposeRandom(80)
+	assert.Equal(t, []string{"80/tcp"}, opt.randomPorts)
+}
+
 func TestExpose_GivenPort_SetsTcpBinding(t *testing.T) {
 	const port = 80
 
@@ -120,6 +148,20 @@ func TestStartContainerPorts_WherePortsAreConfigured_ReturnsPortSets(t *testing.
 	assert.Equal(t, "8080", bindings[0].HostPort)
 }
 
+func TestStartContainerPorts_WhereRandomPortsAreConfigured_ReturnsEmptyHostPort(t *testing.T) {
+	opt := &StartContainerOptions{
+		randomPorts: []string{"80/tcp"},
+	}
+
+	set, pmap, err := opt.Ports()
+	assert.Nil(t, err)
+	assert.NotNil(t, set["80/tcp"])
+
+	bindings := pmap["80/tcp"]
+	assert.Len(t, bindings, 1)
+	assert.Equal(t, "", bindings[0].HostPort)
+}
+
 func TestStartContainerEnvironmentVariables_WhenSet_ReturnsValues(t *testing.T) {
 	opt := &StartContainerOptions{
 		environment: map[string]string{
@@ -161,3 +203,75 @@ func TestStartContainerCapAdd_WhereNotSet_ReturnsValues(t *testing.T) {
 	assert.NotNil(t, caps)
 	assert.Len(t, caps, 0)
 }
+
+func TestWithWaitStrategy_GivenStrategy_SetsStrategy(t *testing.T) {
+	strategy := wait.ForLog("ready")
+
+	opt := WithWaitStrategy(strategy)
+
+	v, ok := opt.WaitStrategy()
+	assert.Equal(t, strategy, v)
+	assert.True(t, ok)
+}
+
+func TestWithVolumeMount_GivenValues_SetsVolumeMount(t *testing.T) {
+	opt := WithVolumeMount("pgdata", "/var/lib/postgresql/data", false)
+
+	assert.Equal(t, []mount.Mount{{
+		Type:   mount.TypeVolume,
+		Source: "pgdata",
+		Target: "/var/lib/postgresql/data",
+	}}, opt.mounts)
+}
+
+func TestWithBindMountPropagation_GivenValues_SetsBindOptions(t *testing.T) {
+	opt := StartContainer().WithBindMountPropagation("/data", "/data", true, mount.PropagationRShared)
+
+	assert.Len(t, opt.mounts, 1)
+	assert.True(t, opt.mounts[0].ReadOnly)
+	assert.Equal(t, mount.PropagationRShared, opt.mounts[0].BindOptions.Propagation)
+}
+
+func TestWithTmpfsMount_GivenValues_SetsTmpfsMount(t *testing.T) {
+	opt := WithTmpfsMount("/tmp", 1024)
+
+	assert.Len(t, opt.mounts, 1)
+	assert.Equal(t, mount.TypeTmpfs, opt.mounts[0].Type)
+	assert.Equal(t, int64(1024), opt.mounts[0].TmpfsOptions.SizeBytes)
+}
+
+func TestStartContainerMounts_WhereBindSourceIsRelative_ReturnsAbsoluteSource(t *testing.T) {
+	opt := WithBindMount("./testdata", "/data", true)
+
+	mounts, err := opt.Mounts()
+	assert.Nil(t, err)
+	expected, _ := filepath.Abs("./testdata")
+	assert.Equal(t, expected, mounts[0].Source)
+	assert.Equal(t, "./testdata", opt.mounts[0].Source)
+}
+
+func TestWithLogConsumer_GivenConsumers_SetsConsumers(t *testing.T) {
+	consumer := logs.ToWriter(&strings.Builder{})
+
+	opt := WithLogConsumer(consumer)
+	assert.Equal(t, []logs.Consumer{consumer}, opt.LogConsumers())
+}
+
+func TestWithPullPolicy_GivenPolicy_SetsPolicyAndOptions(t *testing.T) {
+	pullOptions := WithRegistryCredentials("user", "secret")
+
+	opt := WithPullPolicy(PullNever, pullOptions)
+
+	v, ok := opt.PullPolicy()
+	assert.Equal(t, PullNever, v)
+	assert.True(t, ok)
+	assert.Equal(t, pullOptions, opt.PullOptions())
+}
+
+func TestStartContainerPullPolicy_WithoutPolicy_ReturnsFalse(t *testing.T) {
+	opt := StartContainer()
+
+	_, ok := opt.PullPolicy()
+	assert.False(t, ok)
+	assert.NotNil(t, opt.PullOptions())
+}
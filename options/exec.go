@@ -0,0 +1,125 @@
+package options
+
+import (
+	"fmt"
+	"io"
+)
+
+// ExecOptions is used to pass optional arguments when executing a command
+// inside of a running container.
+type ExecOptions struct {
+	environment map[string]string
+	user        *string
+	workingDir  *string
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+	tty         bool
+}
+
+// Exec returns a new instance of ExecOptions.
+func Exec() *ExecOptions {
+	return &ExecOptions{
+		environment: map[string]string{},
+	}
+}
+
+// EnvironmentVariables is used to retrieve the environment variables configuration
+// for the command. The variables are in the format of "foo=bar".
+func (opt *ExecOptions) EnvironmentVariables() []string {
+	arr := make([]string, 0)
+	for name, value := range opt.environment {
+		arr = append(arr, fmt.Sprintf("%s=%s", name, value))
+	}
+	return arr
+}
+
+// User is used to retrieve the configured user to run the command as. If no
+// user is configured, an empty string followed by a false value is returned.
+func (opt *ExecOptions) User() (string, bool) {
+	if opt.user == nil {
+		return "", false
+	}
+	return *opt.user, true
+}
+
+// WorkingDir is used to retrieve the configured working directory of the command.
+// If no working directory is configured, an empty string followed by a false value
+// is returned.
+func (opt *ExecOptions) WorkingDir() (string, bool) {
+	if opt.workingDir == nil {
+		return "", false
+	}
+	return *opt.workingDir, true
+}
+
+// Stdin is used to retrieve the configured reader which is attached to the
+// command's standard input. If no reader is configured, nil is returned.
+func (opt *ExecOptions) Stdin() io.Reader {
+	return opt.stdin
+}
+
+// Stdout is used to retrieve the configured writer which the command's standard
+// output is streamed to. If no writer is configured, nil is returned.
+func (opt *ExecOptions) Stdout() io.Writer {
+	return opt.stdout
+}
+
+// Stderr is used to retrieve the configured writer which the command's standard
+// error is streamed to. If no writer is configured, nil is returned.
+func (opt *ExecOptions) Stderr() io.Writer {
+	return opt.stderr
+}
+
+// Tty returns true if the command should be run with a TTY attached.
+func (opt *ExecOptions) Tty() bool {
+	return opt.tty
+}
+
+// WithEnvironmentVariable is used to configure a single environment variable.
+func (opt *ExecOptions) WithEnvironmentVariable(name, value string) *ExecOptions {
+	opt.environment[name] = value
+	return opt
+}
+
+// WithEnvironmentVariables is used to configure a collection of environment variables.
+func (opt *ExecOptions) WithEnvironmentVariables(values map[string]string) *ExecOptions {
+	for name, value := range values {
+		opt.WithEnvironmentVariable(name, value)
+	}
+	return opt
+}
+
+// WithUser is used to configure the user, in the format of "user[:group]",
+// which the command is run as.
+func (opt *ExecOptions) WithUser(user string) *ExecOptions {
+	opt.user = &user
+	return opt
+}
+
+// WithWorkingDir is used to configure the working directory of the command.
+func (opt *ExecOptions) WithWorkingDir(dir string) *ExecOptions {
+	opt.workingDir = &dir
+	return opt
+}
+
+// WithStdin is used to attach a reader to the command's standard input.
+func (opt *ExecOptions) WithStdin(r io.Reader) *ExecOptions {
+	opt.stdin = r
+	return opt
+}
+
+// WithOutput is used to stream the command's standard output and error to the
+// given writers as it is produced. The output is still captured in the result.
+func (opt *ExecOptions) WithOutput(stdout, stderr io.Writer) *ExecOptions {
+	opt.stdout = stdout
+	opt.stderr = stderr
+	return opt
+}
+
+// WithTty is used to run the command with a TTY attached. When a TTY is attached,
+// stdout and stderr are combined and all output is reported as stdout.
+func (opt *ExecOptions) WithTty() *ExecOptions {
+	opt.tty = true
+	return opt
+}
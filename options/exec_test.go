@@ -0,0 +1,78 @@
+package options
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExec_WhenCalled_ReturnsNewInstanceWithDefaultValues(t *testing.T) {
+	opt := Exec()
+
+	// Environment Variables
+	assert.Len(t, opt.EnvironmentVariables(), 0)
+
+	// User
+	v, ok := opt.User()
+	assert.Empty(t, v)
+	assert.False(t, ok)
+
+	// Working Directory
+	v, ok = opt.WorkingDir()
+	assert.Empty(t, v)
+	assert.False(t, ok)
+
+	// Streams
+	assert.Nil(t, opt.Stdin())
+	assert.Nil(t, opt.Stdout())
+	assert.Nil(t, opt.Stderr())
+	assert.False(t, opt.Tty())
+}
+
+func TestExecWithEnvironmentVariables_GivenValues_ReturnsValues(t *testing.T) {
+	opt := Exec().WithEnvironmentVariables(map[string]string{
+		"foo": "bar",
+	})
+
+	values := opt.EnvironmentVariables()
+	assert.Equal(t, []string{"foo=bar"}, values)
+}
+
+func TestExecWithUser_GivenUser_SetsUser(t *testing.T) {
+	opt := Exec().WithUser("postgres")
+
+	v, ok := opt.User()
+	assert.Equal(t, "postgres", v)
+	assert.True(t, ok)
+}
+
+func TestExecWithWorkingDir_GivenDir_SetsWorkingDir(t *testing.T) {
+	opt := Exec().WithWorkingDir("/app")
+
+	v, ok := opt.WorkingDir()
+	assert.Equal(t, "/app", v)
+	assert.True(t, ok)
+}
+
+func TestExecWithStdin_GivenReader_SetsStdin(t *testing.T) {
+	r := strings.NewReader("SELECT 1;")
+
+	opt := Exec().WithStdin(r)
+	assert.Equal(t, r, opt.Stdin())
+}
+
+func TestExecWithOutput_GivenWriters_SetsStreams(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	opt := Exec().WithOutput(stdout, stderr)
+	assert.Equal(t, stdout, opt.Stdout())
+	assert.Equal(t, stderr, opt.Stderr())
+}
+
+func TestExecWithTty_WhenCalled_SetsTty(t *testing.T) {
+	opt := Exec().WithTty()
+	assert.True(t, opt.Tty())
+}
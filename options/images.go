@@ -1,14 +1,40 @@
 package options
 
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/registry"
+
+	"github.com/james226/dockerclient/dockerfile"
+	"github.com/james226/dockerclient/progress"
+)
+
 // BuildImageOptions is used to pass optional arguments when building an Image.
 type BuildImageOptions struct {
-	dockerfile string
-	platform   *string
+	dockerfile  string
+	content     *string
+	builder     *dockerfile.Dockerfile
+	platform    *string
+	onProgress  progress.BuildFunc
+	excludes    []string
+	buildArgs   map[string]*string
+	target      string
+	labels      map[string]string
+	tags        []string
+	noCache     bool
+	pullParent  bool
+	cacheFrom   []string
+	networkMode string
+	extraHosts  []string
+	shmSize     int64
 }
 
 // BuildImage returns a new instance of BuildImageOptions.
 func BuildImage() *BuildImageOptions {
-	return &BuildImageOptions{}
+	return &BuildImageOptions{
+		buildArgs: map[string]*string{},
+		labels:    map[string]string{},
+	}
 }
 
 // WithDockerfile is used to specify the path to the Dockerfile.
@@ -17,6 +43,24 @@ func (opt *BuildImageOptions) WithDockerfile(dockerfile string) *BuildImageOptio
 	return opt
 }
 
+// WithDockerfileContent is used to build using the given Dockerfile content
+// instead of reading the Dockerfile from the build context. The content
+// replaces any file at the Dockerfile path.
+func (opt *BuildImageOptions) WithDockerfileContent(content string) *BuildImageOptions {
+	opt.content = &content
+	opt.builder = nil
+	return opt
+}
+
+// WithDockerfileBuilder is used to build using a Dockerfile built with the
+// dockerfile package, the same as WithDockerfileContent. The Dockerfile is
+// validated before the build is started.
+func (opt *BuildImageOptions) WithDockerfileBuilder(d *dockerfile.Dockerfile) *BuildImageOptions {
+	opt.builder = d
+	opt.content = nil
+	return opt
+}
+
 // AsLinuxAmd64 is used to specify the build architecture as linux/amd64.
 func (opt *BuildImageOptions) AsLinuxAmd64() *BuildImageOptions {
 	arch := "linux/amd64"
@@ -24,6 +68,105 @@ func (opt *BuildImageOptions) AsLinuxAmd64() *BuildImageOptions {
 	return opt
 }
 
+// WithPlatform is used to specify the build platform, such as "linux/arm64".
+func (opt *BuildImageOptions) WithPlatform(platform string) *BuildImageOptions {
+	opt.platform = &platform
+	return opt
+}
+
+// WithBuildArg is used to set the value of a single ARG in the Dockerfile.
+func (opt *BuildImageOptions) WithBuildArg(name, value string) *BuildImageOptions {
+	opt.buildArgs[name] = &value
+	return opt
+}
+
+// WithBuildArgs is used to set the values of a collection of ARGs in the Dockerfile.
+func (opt *BuildImageOptions) WithBuildArgs(values map[string]string) *BuildImageOptions {
+	for name, value := range values {
+		opt.WithBuildArg(name, value)
+	}
+	return opt
+}
+
+// WithTarget is used to specify the stage of a multi-stage Dockerfile to build.
+func (opt *BuildImageOptions) WithTarget(target string) *BuildImageOptions {
+	opt.target = target
+	return opt
+}
+
+// WithLabel is used to add a single label to the built image.
+func (opt *BuildImageOptions) WithLabel(name, value string) *BuildImageOptions {
+	opt.labels[name] = value
+	return opt
+}
+
+// WithLabels is used to add a collection of labels to the built image.
+func (opt *BuildImageOptions) WithLabels(values map[string]string) *BuildImageOptions {
+	for name, value := range values {
+		opt.WithLabel(name, value)
+	}
+	return opt
+}
+
+// WithTags is used to tag the built image with extra names, in addition to
+// the name it is built with.
+func (opt *BuildImageOptions) WithTags(tags ...string) *BuildImageOptions {
+	opt.tags = append(opt.tags, tags...)
+	return opt
+}
+
+// WithNoCache is used to build the image without using cached layers.
+func (opt *BuildImageOptions) WithNoCache() *BuildImageOptions {
+	opt.noCache = true
+	return opt
+}
+
+// WithPullParent is used to always pull newer versions of the base images.
+func (opt *BuildImageOptions) WithPullParent() *BuildImageOptions {
+	opt.pullParent = true
+	return opt
+}
+
+// WithCacheFrom is used to specify images which are used as cache sources.
+func (opt *BuildImageOptions) WithCacheFrom(images ...string) *BuildImageOptions {
+	opt.cacheFrom = append(opt.cacheFrom, images...)
+	return opt
+}
+
+// WithNetworkMode is used to specify the network mode of RUN instructions,
+// such as "host" or "none".
+func (opt *BuildImageOptions) WithNetworkMode(mode string) *BuildImageOptions {
+	opt.networkMode = mode
+	return opt
+}
+
+// WithExtraHost is used to add a host to IP mapping to /etc/hosts during the build.
+func (opt *BuildImageOptions) WithExtraHost(host, ip string) *BuildImageOptions {
+	opt.extraHosts = append(opt.extraHosts, host+":"+ip)
+	return opt
+}
+
+// WithShmSize is used to specify the size of /dev/shm during the build, in bytes.
+func (opt *BuildImageOptions) WithShmSize(bytes int64) *BuildImageOptions {
+	opt.shmSize = bytes
+	return opt
+}
+
+// WithProgress is used to configure a function which is called with each event
+// reported while the image is built.
+func (opt *BuildImageOptions) WithProgress(fn progress.BuildFunc) *BuildImageOptions {
+	opt.onProgress = fn
+	return opt
+}
+
+// WithExcludes is used to exclude files from the build context, in addition to
+// those excluded by the .dockerignore file. Patterns use the same syntax as the
+// .dockerignore file, and are applied after it.
+func (opt *BuildImageOptions) WithExcludes(patterns ...string) *BuildImageOptions {
+	opt.excludes = append(opt.excludes, patterns...)
+	return opt
+}
+
 // Dockerfile is used to get the configured Dockerfile path. If no Dockerfile
 // has been configured, "Dockerfile" will be returned as default.
 func (opt *BuildImageOptions) Dockerfile() string {
@@ -33,6 +176,24 @@ func (opt *BuildImageOptions) Dockerfile() string {
 	return opt.dockerfile
 }
 
+// DockerfileContent is used to get the configured Dockerfile content. If no
+// content has been configured, an empty string followed by false is returned.
+// A Dockerfile configured with WithDockerfileBuilder is rendered, returning an
+// error if it is not valid.
+func (opt *BuildImageOptions) DockerfileContent() (string, bool, error) {
+	if opt.builder != nil {
+		err := opt.builder.Validate()
+		if err != nil {
+			return "", false, fmt.Errorf("invalid dockerfile: %w", err)
+		}
+		return opt.builder.String(), true, nil
+	}
+	if opt.content == nil {
+		return "", false, nil
+	}
+	return *opt.content, true, nil
+}
+
 // Platform is used to get the configured platform argument. If the platform
 // has been configured, the value with true is returned, otherwise an empty string
 // with false.
@@ -43,7 +204,450 @@ func (opt *BuildImageOptions) Platform() (string, bool) {
 	return *opt.platform, true
 }
 
+// Progress is used to get the configured progress function. If no function has
+// been configured, nil followed by false is returned.
+func (opt *BuildImageOptions) Progress() (progress.BuildFunc, bool) {
+	if opt.onProgress == nil {
+		return nil, false
+	}
+	return opt.onProgress, true
+}
+
+// Excludes is used to get the configured patterns of files excluded from the
+// build context.
+func (opt *BuildImageOptions) Excludes() []string {
+	return opt.excludes
+}
+
+// BuildArgs is used to get the configured values of the Dockerfile's ARGs.
+func (opt *BuildImageOptions) BuildArgs() map[string]*string {
+	return opt.buildArgs
+}
+
+// Target is used to get the configured stage to build. If no stage has been
+// configured, an empty string is returned and the final stage is built.
+func (opt *BuildImageOptions) Target() string {
+	return opt.target
+}
+
+// Labels is used to get the configured labels of the built image.
+func (opt *BuildImageOptions) Labels() map[string]string {
+	return opt.labels
+}
+
+// Tags is used to get the configured extra names of the built image.
+func (opt *BuildImageOptions) Tags() []string {
+	return opt.tags
+}
+
+// NoCache returns true if the image should be built without cached layers.
+func (opt *BuildImageOptions) NoCache() bool {
+	return opt.noCache
+}
+
+// PullParent returns true if newer versions of the base images should be pulled.
+func (opt *BuildImageOptions) PullParent() bool {
+	return opt.pullParent
+}
+
+// CacheFrom is used to get the configured cache source images.
+func (opt *BuildImageOptions) CacheFrom() []string {
+	return opt.cacheFrom
+}
+
+// NetworkMode is used to get the configured network mode of RUN instructions.
+// If no mode has been configured, an empty string is returned.
+func (opt *BuildImageOptions) NetworkMode() string {
+	return opt.networkMode
+}
+
+// ExtraHosts is used to get the configured host mappings, in the format of "host:ip".
+func (opt *BuildImageOptions) ExtraHosts() []string {
+	return opt.extraHosts
+}
+
+// ShmSize is used to get the configured size of /dev/shm, in bytes. If no
+// size has been configured, 0 is returned and the daemon's default is used.
+func (opt *BuildImageOptions) ShmSize() int64 {
+	return opt.shmSize
+}
+
 // WithDockerfile returns a new instance of BuildImageOptions with the specified Dockerfile.
 func WithDockerfile(dockerfile string) *BuildImageOptions {
 	return BuildImage().WithDockerfile(dockerfile)
 }
+
+// WithBuildProgress returns a new instance of BuildImageOptions with the specified
+// progress function.
+func WithBuildProgress(fn progress.BuildFunc) *BuildImageOptions {
+	return BuildImage().WithProgress(fn)
+}
+
+// WithExcludes returns a new instance of BuildImageOptions with the specified
+// build context exclusions.
+func WithExcludes(patterns ...string) *BuildImageOptions {
+	return BuildImage().WithExcludes(patterns...)
+}
+
+// WithBuildArgs returns a new instance of BuildImageOptions with the specified
+// build arguments.
+func WithBuildArgs(values map[string]string) *BuildImageOptions {
+	return BuildImage().WithBuildArgs(values)
+}
+
+// WithTarget returns a new instance of BuildImageOptions with the specified
+// target stage.
+func WithTarget(target string) *BuildImageOptions {
+	return BuildImage().WithTarget(target)
+}
+
+// PullPolicy is used to decide whether an image is pulled from its registry
+// before it is used.
+type PullPolicy int
+
+const (
+	// PullIfNotPresent pulls the image only when it does not exist locally.
+	PullIfNotPresent PullPolicy = iota
+	// PullAlways pulls the image every time, so that the latest version of
+	// its tag is used.
+	PullAlways
+	// PullNever never pulls the image, and fails when it does not exist locally.
+	PullNever
+)
+
+func (p PullPolicy) String() string {
+	switch p {
+	case PullIfNotPresent:
+		return "IfNotPresent"
+	case PullAlways:
+		return "Always"
+	case PullNever:
+		return "Never"
+	}
+	return fmt.Sprintf("PullPolicy(%d)", int(p))
+}
+
+// PullImageOptions is used to pass optional arguments when pulling an Image.
+type PullImageOptions struct {
+	auth         *registry.AuthConfig
+	dockerConfig string
+	platform     *string
+	onProgress   progress.PullFunc
+	quiet        bool
+}
+
+// PullImage returns a new instance of PullImageOptions.
+func PullImage() *PullImageOptions {
+	return &PullImageOptions{}
+}
+
+// WithCredentials is used to pull using the given username and password,
+// instead of the credentials configured for the Docker CLI.
+func (opt *PullImageOptions) WithCredentials(username, password string) *PullImageOptions {
+	opt.auth = &registry.AuthConfig{Username: username, Password: password}
+	return opt
+}
+
+// WithIdentityToken is used to pull using the given identity token, such as
+// an OAuth refresh token, instead of the credentials configured for the
+// Docker CLI.
+func (opt *PullImageOptions) WithIdentityToken(token string) *PullImageOptions {
+	opt.auth = &registry.AuthConfig{IdentityToken: token}
+	return opt
+}
+
+// WithDockerConfig is used to specify the path of the Docker CLI config file
+// which registry credentials are read from. By default, the config file in
+// the directory set by DOCKER_CONFIG, or ~/.docker, is used.
+func (opt *PullImageOptions) WithDockerConfig(path string) *PullImageOptions {
+	opt.dockerConfig = path
+	return opt
+}
+
+// WithPlatform is used to specify the platform of the image to pull, such as
+// "linux/arm64".
+func (opt *PullImageOptions) WithPlatform(platform string) *PullImageOptions {
+	opt.platform = &platform
+	return opt
+}
+
+// WithProgress is used to configure a function which is called with each event
+// reported while the image is pulled.
+func (opt *PullImageOptions) WithProgress(fn progress.PullFunc) *PullImageOptions {
+	opt.onProgress = fn
+	return opt
+}
+
+// WithQuiet is used to stop the pull status being written to the client's
+// output. Progress events are still reported.
+func (opt *PullImageOptions) WithQuiet() *PullImageOptions {
+	opt.quiet = true
+	return opt
+}
+
+// Credentials is used to get the configured registry credentials. If no
+// credentials have been configured, an empty AuthConfig followed by false is
+// returned, and the credentials configured for the Docker CLI are used.
+func (opt *PullImageOptions) Credentials() (registry.AuthConfig, bool) {
+	if opt.auth == nil {
+		return registry.AuthConfig{}, false
+	}
+	return *opt.auth, true
+}
+
+// DockerConfig is used to get the configured path of the Docker CLI config
+// file. If no path has been configured, an empty string is returned.
+func (opt *PullImageOptions) DockerConfig() string {
+	return opt.dockerConfig
+}
+
+// Platform is used to get the configured platform. If the platform has been
+// configured, the value with true is returned, otherwise an empty string with
+// false.
+func (opt *PullImageOptions) Platform() (string, bool) {
+	if opt.platform == nil {
+		return "", false
+	}
+	return *opt.platform, true
+}
+
+// Progress is used to get the configured progress function. If no function has
+// been configured, nil followed by false is returned.
+func (opt *PullImageOptions) Progress() (progress.PullFunc, bool) {
+	if opt.onProgress == nil {
+		return nil, false
+	}
+	return opt.onProgress, true
+}
+
+// Quiet returns true if the pull status should not be written to the client's output.
+func (opt *PullImageOptions) Quiet() bool {
+	return opt.quiet
+}
+
+// WithRegistryCredentials returns a new instance of PullImageOptions with the
+// specified username and password.
+func WithRegistryCredentials(username, password string) *PullImageOptions {
+	return PullImage().WithCredentials(username, password)
+}
+
+// WithPullProgress returns a new instance of PullImageOptions with the specified
+// progress function.
+func WithPullProgress(fn progress.PullFunc) *PullImageOptions {
+	return PullImage().WithProgress(fn)
+}
+
+// ListImagesOptions is used to pass optional arguments when listing images.
+type ListImagesOptions struct {
+	labels     map[string]string
+	references []string
+	dangling   *bool
+	all        bool
+}
+
+// ListImages returns a new instance of ListImagesOptions.
+func ListImages() *ListImagesOptions {
+	return &ListImagesOptions{
+		labels: map[string]string{},
+	}
+}
+
+// WithLabel is used to only list images which have the given label.
+func (opt *ListImagesOptions) WithLabel(name, value string) *ListImagesOptions {
+	opt.labels[name] = value
+	return opt
+}
+
+// WithLabels is used to only list images which have all of the given labels.
+func (opt *ListImagesOptions) WithLabels(values map[string]string) *ListImagesOptions {
+	for name, value := range values {
+		opt.WithLabel(name, value)
+	}
+	return opt
+}
+
+// WithReference is used to only list images with a name matching the given
+// pattern, such as "postgres" or "registry.example.com/team/*:1.*". When
+// called more than once, images matching any of the patterns are listed.
+func (opt *ListImagesOptions) WithReference(pattern string) *ListImagesOptions {
+	opt.references = append(opt.references, pattern)
+	return opt
+}
+
+// WithDangling is used to only list images without a name, when true, or
+// only images with a name, when false.
+func (opt *ListImagesOptions) WithDangling(dangling bool) *ListImagesOptions {
+	opt.dangling = &dangling
+	return opt
+}
+
+// WithAll is used to also list the intermediate images created by builds.
+func (opt *ListImagesOptions) WithAll() *ListImagesOptions {
+	opt.all = true
+	return opt
+}
+
+// Labels is used to get the configured labels which listed images must have.
+func (opt *ListImagesOptions) Labels() map[string]string {
+	return opt.labels
+}
+
+// References is used to get the configured name patterns of listed images.
+func (opt *ListImagesOptions) References() []string {
+	return opt.references
+}
+
+// Dangling is used to get the configured dangling filter. If no filter has been
+// configured, false followed by false is returned.
+func (opt *ListImagesOptions) Dangling() (bool, bool) {
+	if opt.dangling == nil {
+		return false, false
+	}
+	return *opt.dangling, true
+}
+
+// All returns true if intermediate images should be listed.
+func (opt *ListImagesOptions) All() bool {
+	return opt.all
+}
+
+// WithImageLabels returns a new instance of ListImagesOptions with the specified labels.
+func WithImageLabels(values map[string]string) *ListImagesOptions {
+	return ListImages().WithLabels(values)
+}
+
+// WithReference returns a new instance of ListImagesOptions with the specified
+// name pattern.
+func WithReference(pattern string) *ListImagesOptions {
+	return ListImages().WithReference(pattern)
+}
+
+// RemoveImageOptions is used to pass optional arguments when removing an image.
+type RemoveImageOptions struct {
+	force         bool
+	pruneChildren bool
+}
+
+// RemoveImage returns a new instance of RemoveImageOptions.
+func RemoveImage() *RemoveImageOptions {
+	return &RemoveImageOptions{}
+}
+
+// WithForce is used to remove the image even when it is used by a stopped
+// container, or has other names.
+func (opt *RemoveImageOptions) WithForce() *RemoveImageOptions {
+	opt.force = true
+	return opt
+}
+
+// WithPruneChildren is used to also remove the image's untagged parent images.
+func (opt *RemoveImageOptions) WithPruneChildren() *RemoveImageOptions {
+	opt.pruneChildren = true
+	return opt
+}
+
+// Force returns true if the image should be removed even when it is in use.
+func (opt *RemoveImageOptions) Force() bool {
+	return opt.force
+}
+
+// PruneChildren returns true if the image's untagged parents should be removed.
+func (opt *RemoveImageOptions) PruneChildren() bool {
+	return opt.pruneChildren
+}
+
+// ForceRemove returns a new instance of RemoveImageOptions which forces the
+// image to be removed.
+func ForceRemove() *RemoveImageOptions {
+	return RemoveImage().WithForce()
+}
+
+// PushImageOptions is used to pass optional arguments when pushing an Image.
+type PushImageOptions struct {
+	auth         *registry.AuthConfig
+	dockerConfig string
+	onProgress   progress.PushFunc
+	quiet        bool
+}
+
+// PushImage returns a new instance of PushImageOptions.
+func PushImage() *PushImageOptions {
+	return &PushImageOptions{}
+}
+
+// WithCredentials is used to push using the given username and password,
+// instead of the credentials configured for the Docker CLI.
+func (opt *PushImageOptions) WithCredentials(username, password string) *PushImageOptions {
+	opt.auth = &registry.AuthConfig{Username: username, Password: password}
+	return opt
+}
+
+// WithIdentityToken is used to push using the given identity token, instead of
+// the credentials configured for the Docker CLI.
+func (opt *PushImageOptions) WithIdentityToken(token string) *PushImageOptions {
+	opt.auth = &registry.AuthConfig{IdentityToken: token}
+	return opt
+}
+
+// WithDockerConfig is used to specify the path of the Docker CLI config file
+// which registry credentials are read from.
+func (opt *PushImageOptions) WithDockerConfig(path string) *PushImageOptions {
+	opt.dockerConfig = path
+	return opt
+}
+
+// WithProgress is used to configure a function which is called with each event
+// reported while the image is pushed.
+func (opt *PushImageOptions) WithProgress(fn progress.PushFunc) *PushImageOptions {
+	opt.onProgress = fn
+	return opt
+}
+
+// WithQuiet is used to stop the push status being written to the client's
+// output. Progress events are still reported.
+func (opt *PushImageOptions) WithQuiet() *PushImageOptions {
+	opt.quiet = true
+	return opt
+}
+
+// Credentials is used to get the configured registry credentials. If no
+// credentials have been configured, an empty AuthConfig followed by false is
+// returned, and the credentials configured for the Docker CLI are used.
+func (opt *PushImageOptions) Credentials() (registry.AuthConfig, bool) {
+	if opt.auth == nil {
+		return registry.AuthConfig{}, false
+	}
+	return *opt.auth, true
+}
+
+// DockerConfig is used to get the configured path of the Docker CLI config
+// file. If no path has been configured, an empty string is returned.
+func (opt *PushImageOptions) DockerConfig() string {
+	return opt.dockerConfig
+}
+
+// Progress is used to get the configured progress function. If no function has
+// been configured, nil followed by false is returned.
+func (opt *PushImageOptions) Progress() (progress.PushFunc, bool) {
+	if opt.onProgress == nil {
+		return nil, false
+	}
+	return opt.onProgress, true
+}
+
+// Quiet returns true if the push status should not be written to the client's output.
+func (opt *PushImageOptions) Quiet() bool {
+	return opt.quiet
+}
+
+// WithPushCredentials returns a new instance of PushImageOptions with the
+// specified username and password.
+func WithPushCredentials(username, password string) *PushImageOptions {
+	return PushImage().WithCredentials(username, password)
+}
+
+// WithPushProgress returns a new instance of PushImageOptions with the specified
+// progress function.
+func WithPushProgress(fn progress.PushFunc) *PushImageOptions {
+	return PushImage().WithProgress(fn)
+}
@@ -4,6 +4,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/dockerfile"
+
+	"github.com/james226/dockerclient/progress"
 )
 
 func TestBuildImage_WhenCalled_ReturnsDefaultConfig(t *testing.T) {
@@ -16,6 +20,11 @@ func TestBuildImage_WhenCalled_ReturnsDefaultConfig(t *testing.T) {
 	v, ok := opt.Platform()
 	assert.Empty(t, v)
 	assert.False(t, ok)
+
+	// Progress
+	fn, ok := opt.Progress()
+	assert.Nil(t, fn)
+	assert.False(t, ok)
 }
 
 func TestWithDockerfile_GivenNonEmptyPath_SetsDockerfile(t *testing.T) {
@@ -31,6 +40,39 @@ func TestBuildImageDockerfile_WithoutSpecifyingDockerfile_ReturnsDefault(t *test
 	assert.Equal(t, "Dockerfile", opt.Dockerfile())
 }
 
+func TestBuildImageWithDockerfileContent_GivenContent_SetsContent(t *testing.T) {
+	opt := BuildImage()
+	v, ok, err := opt.DockerfileContent()
+	assert.Nil(t, err)
+	assert.Empty(t, v)
+	assert.False(t, ok)
+
+	opt.WithDockerfileContent("FROM alpine\n")
+
+	v, ok, err = opt.DockerfileContent()
+	assert.Nil(t, err)
+	assert.Equal(t, "FROM alpine\n", v)
+	assert.True(t, ok)
+}
+
+func TestBuildImageWithDockerfileBuilder_GivenDockerfile_RendersContent(t *testing.T) {
+	opt := BuildImage().WithDockerfileBuilder(dockerfile.New().From("alpine").Run("apk add curl"))
+
+	v, ok, err := opt.DockerfileContent()
+
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "FROM alpine\nRUN apk add curl\n", v)
+}
+
+func TestBuildImageWithDockerfileBuilder_GivenInstructionBeforeFrom_ReturnsError(t *testing.T) {
+	opt := BuildImage().WithDockerfileBuilder(dockerfile.New().Run("apk add curl").From("alpine"))
+
+	_, _, err := opt.DockerfileContent()
+
+	assert.ErrorContains(t, err, "before the first FROM")
+}
+
 func TestAsLinuxAmd64_WhenCalled_SetsPlatform(t *testing.T) {
 	opt := BuildImage().AsLinuxAmd64()
 
@@ -38,3 +80,179 @@ func TestAsLinuxAmd64_WhenCalled_SetsPlatform(t *testing.T) {
 	assert.Equal(t, "linux/amd64", v)
 	assert.True(t, ok)
 }
+
+func TestWithBuildProgress_GivenFunction_SetsProgress(t *testing.T) {
+	called := false
+
+	opt := WithBuildProgress(func(event progress.BuildEvent) {
+		called = true
+	})
+
+	fn, ok := opt.Progress()
+	assert.True(t, ok)
+	fn(progress.BuildEvent{})
+	assert.True(t, called)
+}
+
+func TestWithExcludes_GivenPatterns_SetsExcludes(t *testing.T) {
+	opt := WithExcludes("**/*.log").WithExcludes("!keep.log")
+
+	assert.Equal(t, []string{"**/*.log", "!keep.log"}, opt.Excludes())
+}
+
+func TestBuildImage_WhenCalled_ReturnsEmptyBuildConfig(t *testing.T) {
+	opt := BuildImage()
+
+	assert.Len(t, opt.BuildArgs(), 0)
+	assert.Empty(t, opt.Target())
+	assert.Len(t, opt.Labels(), 0)
+	assert.Len(t, opt.Tags(), 0)
+	assert.False(t, opt.NoCache())
+	assert.False(t, opt.PullParent())
+	assert.Len(t, opt.CacheFrom(), 0)
+	assert.Empty(t, opt.NetworkMode())
+	assert.Len(t, opt.ExtraHosts(), 0)
+	assert.Zero(t, opt.ShmSize())
+}
+
+func TestWithBuildArgs_GivenValues_SetsBuildArgs(t *testing.T) {
+	opt := WithBuildArgs(map[string]string{"GO_VERSION": "1.24"})
+
+	v, ok := opt.BuildArgs()["GO_VERSION"]
+	assert.True(t, ok)
+	assert.Equal(t, "1.24", *v)
+}
+
+func TestWithTarget_GivenStage_SetsTarget(t *testing.T) {
+	opt := WithTarget("test")
+	assert.Equal(t, "test", opt.Target())
+}
+
+func TestBuildImageBuilders_GivenValues_SetsValues(t *testing.T) {
+	opt := BuildImage().
+		WithPlatform("linux/arm64").
+		WithLabel("app", "api").
+		WithTags("api:latest", "api:candidate").
+		WithNoCache().
+		WithPullParent().
+		WithCacheFrom("api:cache").
+		WithNetworkMode("host").
+		WithExtraHost("registry.local", "10.0.0.2").
+		WithShmSize(64 * 1024 * 1024)
+
+	platform, ok := opt.Platform()
+	assert.True(t, ok)
+	assert.Equal(t, "linux/arm64", platform)
+	assert.Equal(t, map[string]string{"app": "api"}, opt.Labels())
+	assert.Equal(t, []string{"api:latest", "api:candidate"}, opt.Tags())
+	assert.True(t, opt.NoCache())
+	assert.True(t, opt.PullParent())
+	assert.Equal(t, []string{"api:cache"}, opt.CacheFrom())
+	assert.Equal(t, "host", opt.NetworkMode())
+	assert.Equal(t, []string{"registry.local:10.0.0.2"}, opt.ExtraHosts())
+	assert.Equal(t, int64(64*1024*1024), opt.ShmSize())
+}
+
+func TestPullImage_WhenCalled_ReturnsDefaultConfig(t *testing.T) {
+	opt := PullImage()
+
+	creds, ok := opt.Credentials()
+	assert.Empty(t, creds)
+	assert.False(t, ok)
+	assert.Empty(t, opt.DockerConfig())
+	v, ok := opt.Platform()
+	assert.Empty(t, v)
+	assert.False(t, ok)
+}
+
+func TestWithRegistryCredentials_GivenCredentials_SetsCredentials(t *testing.T) {
+	opt := WithRegistryCredentials("user", "secret")
+
+	creds, ok := opt.Credentials()
+	assert.True(t, ok)
+	assert.Equal(t, "user", creds.Username)
+	assert.Equal(t, "secret", creds.Password)
+}
+
+func TestPullImageWithIdentityToken_GivenToken_SetsCredentials(t *testing.T) {
+	opt := PullImage().WithIdentityToken("refresh")
+
+	creds, ok := opt.Credentials()
+	assert.True(t, ok)
+	assert.Equal(t, "refresh", creds.IdentityToken)
+}
+
+func TestWithPullProgress_GivenFunction_SetsProgress(t *testing.T) {
+	called := false
+
+	opt := WithPullProgress(func(event progress.PullEvent) {
+		called = true
+	})
+
+	fn, ok := opt.Progress()
+	assert.True(t, ok)
+	fn(progress.PullEvent{})
+	assert.True(t, called)
+	assert.False(t, opt.Quiet())
+}
+
+func TestPullImageWithQuiet_WhenCalled_SetsQuiet(t *testing.T) {
+	opt := PullImage().WithQuiet()
+	assert.True(t, opt.Quiet())
+}
+
+func TestPullPolicyString_GivenPolicy_ReturnsName(t *testing.T) {
+	assert.Equal(t, "IfNotPresent", PullIfNotPresent.String())
+	assert.Equal(t, "Always", PullAlways.String())
+	assert.Equal(t, "Never", PullNever.String())
+}
+
+func TestListImages_GivenFilters_SetsFilters(t *testing.T) {
+	opt := ListImages()
+	_, ok := opt.Dangling()
+	assert.False(t, ok)
+
+	opt = WithImageLabels(map[string]string{"team": "core"}).
+		WithReference("app").
+		WithReference("api").
+		WithDangling(false).
+		WithAll()
+
+	assert.Equal(t, map[string]string{"team": "core"}, opt.Labels())
+	assert.Equal(t, []string{"app", "api"}, opt.References())
+	v, ok := opt.Dangling()
+	assert.False(t, v)
+	assert.True(t, ok)
+	assert.True(t, opt.All())
+}
+
+func TestRemoveImage_GivenOptions_SetsOptions(t *testing.T) {
+	opt := RemoveImage()
+	assert.False(t, opt.Force())
+	assert.False(t, opt.PruneChildren())
+
+	opt = ForceRemove().WithPruneChildren()
+	assert.True(t, opt.Force())
+	assert.True(t, opt.PruneChildren())
+}
+
+func TestPushImage_GivenOptions_SetsOptions(t *testing.T) {
+	opt := PushImage()
+	_, ok := opt.Credentials()
+	assert.False(t, ok)
+	_, ok = opt.Progress()
+	assert.False(t, ok)
+
+	opt = WithPushCredentials("user", "secret").
+		WithDockerConfig("/tmp/config.json").
+		WithProgress(func(event progress.PushEvent) {}).
+		WithQuiet()
+
+	creds, ok := opt.Credentials()
+	assert.True(t, ok)
+	assert.Equal(t, "user", creds.Username)
+	assert.Equal(t, "/tmp/config.json", opt.DockerConfig())
+	_, ok = opt.Progress()
+	assert.True(t, ok)
+	assert.True(t, opt.Quiet())
+}
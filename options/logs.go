@@ -0,0 +1,108 @@
+package options
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/james226/dockerclient/logs"
+)
+
+// LogsOptions is used to pass optional arguments when reading a container's logs.
+type LogsOptions struct {
+	follow     bool
+	since      *time.Time
+	until      *time.Time
+	tail       *int
+	timestamps bool
+	consumers  []logs.Consumer
+}
+
+// Logs returns a new instance of LogsOptions.
+func Logs() *LogsOptions {
+	return &LogsOptions{}
+}
+
+// Follow returns true if new output should be streamed until the container stops.
+func (opt *LogsOptions) Follow() bool {
+	return opt.follow
+}
+
+// Since is used to retrieve the time from which logs are read, in RFC 3339 format.
+// If no time is configured, an empty string is returned.
+func (opt *LogsOptions) Since() string {
+	if opt.since == nil {
+		return ""
+	}
+	return opt.since.Format(time.RFC3339Nano)
+}
+
+// Until is used to retrieve the time up to which logs are read, in RFC 3339 format.
+// If no time is configured, an empty string is returned.
+func (opt *LogsOptions) Until() string {
+	if opt.until == nil {
+		return ""
+	}
+	return opt.until.Format(time.RFC3339Nano)
+}
+
+// Tail is used to retrieve the number of lines to read from the end of the logs.
+// If no number is configured, "all" is returned as default.
+func (opt *LogsOptions) Tail() string {
+	if opt.tail == nil {
+		return "all"
+	}
+	return strconv.Itoa(*opt.tail)
+}
+
+// Timestamps returns true if each line should be timestamped by Docker.
+func (opt *LogsOptions) Timestamps() bool {
+	return opt.timestamps
+}
+
+// Consumers returns the consumers which each line of the logs is delivered to.
+func (opt *LogsOptions) Consumers() []logs.Consumer {
+	return opt.consumers
+}
+
+// WithFollow is used to keep streaming new output until the container stops,
+// or the context is cancelled.
+func (opt *LogsOptions) WithFollow() *LogsOptions {
+	opt.follow = true
+	return opt
+}
+
+// WithSince is used to only read logs written at or after the given time.
+func (opt *LogsOptions) WithSince(t time.Time) *LogsOptions {
+	opt.since = &t
+	return opt
+}
+
+// WithUntil is used to only read logs written before the given time.
+func (opt *LogsOptions) WithUntil(t time.Time) *LogsOptions {
+	opt.until = &t
+	return opt
+}
+
+// WithTail is used to only read the given number of lines from the end of the logs.
+func (opt *LogsOptions) WithTail(lines int) *LogsOptions {
+	opt.tail = &lines
+	return opt
+}
+
+// WithTimestamps is used to request the time each line was written from Docker.
+func (opt *LogsOptions) WithTimestamps() *LogsOptions {
+	opt.timestamps = true
+	return opt
+}
+
+// WithConsumer is used to register consumers which each line is delivered to.
+func (opt *LogsOptions) WithConsumer(consumers ...logs.Consumer) *LogsOptions {
+	opt.consumers = append(opt.consumers, consumers...)
+	return opt
+}
+
+// FollowLogs returns a new instance of LogsOptions which streams new output to
+// the given consumers until the container stops.
+func FollowLogs(consumers ...logs.Consumer) *LogsOptions {
+	return Logs().WithFollow().WithConsumer(consumers...)
+}
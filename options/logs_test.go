@@ -0,0 +1,44 @@
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/logs"
+)
+
+func TestLogs_WhenCalled_ReturnsDefaultConfig(t *testing.T) {
+	opt := Logs()
+
+	assert.False(t, opt.Follow())
+	assert.Empty(t, opt.Since())
+	assert.Empty(t, opt.Until())
+	assert.Equal(t, "all", opt.Tail())
+	assert.False(t, opt.Timestamps())
+	assert.Len(t, opt.Consumers(), 0)
+}
+
+func TestLogsWithSinceAndUntil_GivenTimes_ReturnsRFC3339(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	opt := Logs().WithSince(since).WithUntil(since.Add(time.Minute))
+
+	assert.Equal(t, "2024-01-02T03:04:05Z", opt.Since())
+	assert.Equal(t, "2024-01-02T03:05:05Z", opt.Until())
+}
+
+func TestLogsWithTail_GivenLines_ReturnsLines(t *testing.T) {
+	opt := Logs().WithTail(10)
+	assert.Equal(t, "10", opt.Tail())
+}
+
+func TestFollowLogs_GivenConsumers_SetsFollowAndConsumers(t *testing.T) {
+	consumer := logs.NewRingBuffer(10)
+
+	opt := FollowLogs(consumer)
+
+	assert.True(t, opt.Follow())
+	assert.Equal(t, []logs.Consumer{consumer}, opt.Consumers())
+}
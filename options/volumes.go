@@ -0,0 +1,94 @@
+package options
+
+// CreateVolumeOptions is used to pass optional arguments when creating a volume.
+type CreateVolumeOptions struct {
+	driver     *string
+	driverOpts map[string]string
+	labels     map[string]string
+}
+
+// CreateVolume returns a new instance of CreateVolumeOptions.
+func CreateVolume() *CreateVolumeOptions {
+	return &CreateVolumeOptions{
+		driverOpts: map[string]string{},
+		labels:     map[string]string{},
+	}
+}
+
+// Driver is used to retrieve the configured volume driver. If no driver is
+// configured, "local" is returned as default.
+func (opt *CreateVolumeOptions) Driver() string {
+	if opt.driver == nil {
+		return "local"
+	}
+	return *opt.driver
+}
+
+// DriverOpts returns the configured driver specific options.
+func (opt *CreateVolumeOptions) DriverOpts() map[string]string {
+	return opt.driverOpts
+}
+
+// Labels returns the configured volume labels.
+func (opt *CreateVolumeOptions) Labels() map[string]string {
+	return opt.labels
+}
+
+// WithDriver is used to configure the driver used to create the volume.
+func (opt *CreateVolumeOptions) WithDriver(driver string) *CreateVolumeOptions {
+	opt.driver = &driver
+	return opt
+}
+
+// WithDriverOpt is used to configure a single driver specific option.
+func (opt *CreateVolumeOptions) WithDriverOpt(name, value string) *CreateVolumeOptions {
+	opt.driverOpts[name] = value
+	return opt
+}
+
+// WithLabel is used to configure a single volume label.
+func (opt *CreateVolumeOptions) WithLabel(name, value string) *CreateVolumeOptions {
+	opt.labels[name] = value
+	return opt
+}
+
+// WithLabels is used to configure a collection of volume labels.
+func (opt *CreateVolumeOptions) WithLabels(values map[string]string) *CreateVolumeOptions {
+	for name, value := range values {
+		opt.WithLabel(name, value)
+	}
+	return opt
+}
+
+// WithVolumeDriver returns a new instance of CreateVolumeOptions with the specified driver.
+func WithVolumeDriver(driver string) *CreateVolumeOptions {
+	return CreateVolume().WithDriver(driver)
+}
+
+// WithVolumeLabels returns a new instance of CreateVolumeOptions with the specified labels.
+func WithVolumeLabels(values map[string]string) *CreateVolumeOptions {
+	return CreateVolume().WithLabels(values)
+}
+
+// PruneVolumesOptions is used to pass optional arguments when pruning volumes.
+type PruneVolumesOptions struct {
+	all bool
+}
+
+// PruneVolumes returns a new instance of PruneVolumesOptions. By default only
+// unused anonymous volumes are pruned.
+func PruneVolumes() *PruneVolumesOptions {
+	return &PruneVolumesOptions{}
+}
+
+// All returns whether unused named volumes are pruned as well as anonymous ones.
+func (opt *PruneVolumesOptions) All() bool {
+	return opt.all
+}
+
+// WithAll is used to also prune unused named volumes, deleting any data which
+// they hold.
+func (opt *PruneVolumesOptions) WithAll() *PruneVolumesOptions {
+	opt.all = true
+	return opt
+}
@@ -0,0 +1,33 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateVolume_WhenCalled_ReturnsDefaultConfig(t *testing.T) {
+	opt := CreateVolume()
+
+	assert.Equal(t, "local", opt.Driver())
+	assert.Len(t, opt.DriverOpts(), 0)
+	assert.Len(t, opt.Labels(), 0)
+}
+
+func TestWithVolumeDriver_GivenDriver_SetsDriver(t *testing.T) {
+	opt := WithVolumeDriver("nfs").WithDriverOpt("type", "nfs")
+
+	assert.Equal(t, "nfs", opt.Driver())
+	assert.Equal(t, map[string]string{"type": "nfs"}, opt.DriverOpts())
+}
+
+func TestWithVolumeLabels_GivenValues_SetsLabels(t *testing.T) {
+	opt := WithVolumeLabels(map[string]string{"app": "db"})
+
+	assert.Equal(t, map[string]string{"app": "db"}, opt.Labels())
+}
+
+func TestPruneVolumes_WhenCalled_PrunesAnonymousVolumesOnly(t *testing.T) {
+	assert.False(t, PruneVolumes().All())
+	assert.True(t, PruneVolumes().WithAll().All())
+}
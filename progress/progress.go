@@ -0,0 +1,58 @@
+// Package progress defines the events reported while images are built, pulled
+// and pushed.
+package progress
+
+// BuildEvent describes a single piece of output from an image build.
+type BuildEvent struct {
+	// Step and TotalSteps identify the Dockerfile instruction being run, such
+	// as step 3 of 7. They are zero for output before the first step.
+	Step       int
+	TotalSteps int
+	// Instruction is the Dockerfile instruction of the current step, such as
+	// "RUN make build".
+	Instruction string
+	// Message is a single line of output from the build.
+	Message string
+	// CacheHit is true when the current step was satisfied by a cached layer.
+	CacheHit bool
+	// ImageID is only set once the image has been built.
+	ImageID string
+}
+
+// BuildFunc is called with each event reported while building an image.
+type BuildFunc func(event BuildEvent)
+
+// PullEvent describes the progress of a single layer, or of the image as a
+// whole, while pulling an image.
+type PullEvent struct {
+	// LayerID is the short ID of the layer, such as "a3ed95caeb02". It is empty
+	// for events which describe the image as a whole, such as its digest.
+	LayerID string
+	// Status is the state of the layer, such as "Downloading" or "Pull complete".
+	Status string
+	// Current and Total are the number of bytes of the layer downloaded or
+	// extracted so far, and in total. They are zero when the status has no
+	// progress, and Total is zero when the size of the layer is unknown.
+	Current int64
+	Total   int64
+}
+
+// PullFunc is called with each event reported while pulling an image.
+type PullFunc func(event PullEvent)
+
+// PushEvent describes the progress of a single layer, or of the image as a
+// whole, while pushing an image.
+type PushEvent struct {
+	// LayerID is the short ID of the layer, such as "a3ed95caeb02". It is empty
+	// for events which describe the image as a whole.
+	LayerID string
+	// Status is the state of the layer, such as "Pushing" or "Layer already exists".
+	Status string
+	// Current and Total are the number of bytes of the layer uploaded so far,
+	// and in total. They are zero when the status has no progress.
+	Current int64
+	Total   int64
+}
+
+// PushFunc is called with each event reported while pushing an image.
+type PushFunc func(event PushEvent)
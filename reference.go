@@ -0,0 +1,248 @@
+package dockerclient
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+const (
+	// DefaultRegistry is the registry of image references which do not name
+	// a registry, such as "postgres:16".
+	DefaultRegistry = "docker.io"
+	// DefaultTag is the tag of image references which have neither a tag nor
+	// a digest.
+	DefaultTag = "latest"
+)
+
+// Characters which are not allowed in container names.
+var containerNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Reference is a parsed and normalized image reference, such as
+// "registry.example.com:5000/team/app:1.0" or "postgres@sha256:9f1c...".
+type Reference struct {
+	// Registry is the host of the registry, such as "registry.example.com:5000".
+	// It is DefaultRegistry for images on Docker Hub.
+	Registry string
+	// Repository is the path of the image within the registry, such as
+	// "team/app". Official Docker Hub images are in the "library" namespace.
+	Repository string
+	// Tag is the tag of the image. It is DefaultTag when the reference has
+	// neither a tag nor a digest, and empty when it only has a digest.
+	Tag string
+	// Digest is the content digest of the image, such as "sha256:9f1c...".
+	Digest string
+}
+
+// ParseReference is used to parse and normalize an image reference, in the same
+// way as the Docker CLI. For example, "postgres" is normalized to
+// "docker.io/library/postgres:latest".
+func ParseReference(s string) (Reference, error) {
+	named, err := reference.ParseNormalizedNamed(s)
+	if err != nil {
+		return Reference{}, fmt.Errorf("invalid image reference '%s': %w", s, err)
+	}
+	ref := Reference{
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		ref.Tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		ref.Digest = digested.Digest().String()
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = DefaultTag
+	}
+	return ref, nil
+}
+
+// Name is used to get the repository in its shortest form, as shown by the
+// Docker CLI, such as "postgres" for "docker.io/library/postgres".
+func (r Reference) Name() string {
+	if r.Registry != DefaultRegistry {
+		return r.Registry + "/" + r.Repository
+	}
+	name := strings.TrimPrefix(r.Repository, "library/")
+	if strings.Contains(name, "/") {
+		return r.Repository
+	}
+	return name
+}
+
+// FullName is used to get the repository including its registry, such as
+// "docker.io/library/postgres".
+func (r Reference) FullName() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String is used to get the reference in its shortest form, such as
+// "postgres:16" or "registry.example.com/app@sha256:9f1c...".
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// WithTag is used to get a copy of the reference with the given tag, and
+// without a digest.
+func (r Reference) WithTag(tag string) Reference {
+	r.Tag = tag
+	r.Digest = ""
+	return r
+}
+
+// ContainerName is used to derive a valid container name from the reference,
+// made up of the last part of the repository and the tag, such as "app-1.0"
+// for "registry.example.com:5000/team/app:1.0". References with only a digest
+// use the start of the digest in place of the tag.
+func (r Reference) ContainerName() string {
+	suffix := r.Tag
+	if suffix == "" && r.Digest != "" {
+		_, hex, _ := strings.Cut(r.Digest, ":")
+		suffix = hex[:min(12, len(hex))]
+	}
+	return containerName(path.Base(r.Repository) + "-" + suffix)
+}
+
+// Reference is used to parse the name of the image.
+func (i *Image) Reference() (Reference, error) {
+	return ParseReference(i.Name)
+}
+
+// Used to get the name of a container when none is given. It is derived from
+// the image's reference, or from its name if it is not a valid reference, such
+// as an image ID.
+func defaultContainerName(image *Image) string {
+	ref, err := image.Reference()
+	if err != nil {
+		return containerName(image.Name)
+	}
+	return ref.ContainerName()
+}
+
+// Used to replace the characters which are not allowed in container names. A
+// container name must also start with a letter or digit.
+func containerName(s string) string {
+	s = containerNameInvalid.ReplaceAllString(s, "-")
+	s = strings.TrimLeft(s, "_.-")
+	s = strings.TrimRight(s, "-")
+	if s == "" {
+		return "container"
+	}
+	return s
+}
+
+// Registries which are aliases of Docker Hub, as well as DefaultRegistry.
+var dockerHubAliases = []string{"index.docker.io", "registry-1.docker.io"}
+
+// Used to rewrite image references with the registry mirrors and tag rewrites
+// configured for the client.
+type imageRewrites struct {
+	// Mirrors are keyed by the normalized registry which they mirror, such as
+	// "docker.io".
+	mirrors map[string]string
+	// Tags are keyed by the normalized repository and tag which they replace,
+	// such as "docker.io/library/postgres:16".
+	tags map[string]string
+}
+
+// Used to normalize the registry mirrors and tag rewrites configured for the
+// client, so that rewriting a reference only needs a lookup of each. Invalid
+// rules, and rules which normalize to the same registry or image, are rejected.
+func newImageRewrites(mirrors, tags map[string]string) (imageRewrites, error) {
+	r := imageRewrites{
+		mirrors: make(map[string]string, len(mirrors)),
+		tags:    make(map[string]string, len(tags)),
+	}
+	for registry, mirror := range mirrors {
+		key, err := normalizeRegistry(registry)
+		if err != nil {
+			return imageRewrites{}, err
+		}
+		if _, ok := r.mirrors[key]; ok {
+			return imageRewrites{}, fmt.Errorf("registry '%s' has more than one mirror", key)
+		}
+		mirror = strings.TrimSuffix(mirror, "/")
+		_, err = reference.ParseNormalizedNamed(mirror)
+		if err != nil {
+			return imageRewrites{}, fmt.Errorf("invalid registry mirror '%s': %w", mirror, err)
+		}
+		r.mirrors[key] = mirror
+	}
+	for image, tag := range tags {
+		from, err := ParseReference(image)
+		if err != nil {
+			return imageRewrites{}, fmt.Errorf("invalid tag rewrite: %w", err)
+		}
+		if from.Digest != "" {
+			return imageRewrites{}, fmt.Errorf("invalid tag rewrite: image '%s' is pinned to a digest", image)
+		}
+		_, err = ParseReference(from.WithTag(tag).String())
+		if err != nil {
+			return imageRewrites{}, fmt.Errorf("invalid tag rewrite for '%s': %w", image, err)
+		}
+		key := from.FullName() + ":" + from.Tag
+		if _, ok := r.tags[key]; ok {
+			return imageRewrites{}, fmt.Errorf("image '%s' has more than one tag rewrite", from)
+		}
+		r.tags[key] = tag
+	}
+	return r, nil
+}
+
+// Used to normalize the host of a registry, so that aliases of Docker Hub, such
+// as "index.docker.io", are the same as DefaultRegistry.
+func normalizeRegistry(registry string) (string, error) {
+	host := strings.ToLower(strings.TrimSuffix(registry, "/"))
+	if slices.Contains(dockerHubAliases, host) {
+		return DefaultRegistry, nil
+	}
+	named, err := reference.ParseNormalizedNamed(host + "/image")
+	if err != nil || reference.Domain(named) != host {
+		return "", fmt.Errorf("invalid registry '%s'", registry)
+	}
+	return reference.Domain(named), nil
+}
+
+// Used to apply the rewrites to an image reference. Tags are rewritten first,
+// and then the registry is replaced by its mirror. References pinned to a digest
+// keep their tag. Names which no rewrite applies to, and names which are not
+// references, such as image IDs, are returned unchanged.
+func (r imageRewrites) rewrite(name string) (string, error) {
+	// Image IDs would otherwise be parsed as a repository named "sha256".
+	if len(r.mirrors) == 0 && len(r.tags) == 0 || strings.HasPrefix(name, "sha256:") {
+		return name, nil
+	}
+	ref, err := ParseReference(name)
+	if err != nil {
+		return name, nil
+	}
+	rewritten := ref
+	if tag, ok := r.tags[ref.FullName()+":"+ref.Tag]; ok && ref.Digest == "" {
+		rewritten = ref.WithTag(tag)
+	}
+	if mirror, ok := r.mirrors[rewritten.Registry]; ok {
+		mirrored, err := ParseReference(mirror + "/" + rewritten.Repository)
+		if err != nil {
+			return "", fmt.Errorf("invalid registry mirror '%s': %w", mirror, err)
+		}
+		mirrored.Tag = rewritten.Tag
+		mirrored.Digest = rewritten.Digest
+		rewritten = mirrored
+	}
+	if rewritten == ref {
+		return name, nil
+	}
+	return rewritten.String(), nil
+}
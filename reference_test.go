@@ -0,0 +1,127 @@
+package dockerclient
+
+import (
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/options"
+)
+
+func TestParseReference_GivenShortName_NormalizesToDockerHub(t *testing.T) {
+	ref, err := ParseReference("postgres")
+
+	assert.Nil(t, err)
+	assert.Equal(t, Reference{Registry: "docker.io", Repository: "library/postgres", Tag: "latest"}, ref)
+	assert.Equal(t, "postgres:latest", ref.String())
+	assert.Equal(t, "docker.io/library/postgres", ref.FullName())
+}
+
+func TestParseReference_GivenRegistryWithPort_ParsesAllParts(t *testing.T) {
+	digest := "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	ref, err := ParseReference("registry.example.com:5000/team/app:1.0@" + digest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "registry.example.com:5000", ref.Registry)
+	assert.Equal(t, "team/app", ref.Repository)
+	assert.Equal(t, "1.0", ref.Tag)
+	assert.Equal(t, digest, ref.Digest)
+	assert.Equal(t, "registry.example.com:5000/team/app:1.0@"+digest, ref.String())
+}
+
+func TestParseReference_GivenInvalidReference_ReturnsError(t *testing.T) {
+	_, err := ParseReference("Invalid/Name:tag")
+
+	assert.NotNil(t, err)
+}
+
+func TestReference_Name_GivenDockerHubNamespace_KeepsNamespace(t *testing.T) {
+	ref, err := ParseReference("docker.io/bitnami/redis:7")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "bitnami/redis", ref.Name())
+	assert.Equal(t, "bitnami/redis:9", ref.WithTag("9").String())
+}
+
+func TestReference_ContainerName_ReturnsValidName(t *testing.T) {
+	tests := map[string]string{
+		"postgres":                               "postgres-latest",
+		"registry.example.com:5000/team/app:1.0": "app-1.0",
+		"app@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855": "app-e3b0c44298fc",
+	}
+	for s, expected := range tests {
+		ref, err := ParseReference(s)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, ref.ContainerName(), s)
+	}
+}
+
+func TestDefaultContainerName_GivenImageID_ReturnsValidName(t *testing.T) {
+	name := defaultContainerName(&Image{Name: "sha256:4b825dc642cb"})
+
+	assert.Equal(t, "sha256-4b825dc642cb", name)
+}
+
+func TestImageRewrites_GivenRules_RewritesMatchingReferences(t *testing.T) {
+	rewrites, err := newImageRewrites(
+		map[string]string{"index.docker.io": "mirror.example.com/dockerhub/"},
+		map[string]string{"postgres:16": "16.4", "docker.io/library/redis": "7-alpine"},
+	)
+	assert.Nil(t, err)
+	digest := "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tests := map[string]string{
+		"postgres:16":                           "mirror.example.com/dockerhub/library/postgres:16.4",
+		"redis":                                 "mirror.example.com/dockerhub/library/redis:7-alpine",
+		"bitnami/redis:7":                       "mirror.example.com/dockerhub/bitnami/redis:7",
+		"postgres@" + digest:                    "mirror.example.com/dockerhub/library/postgres@" + digest,
+		"registry.example.com/team/postgres:16": "registry.example.com/team/postgres:16",
+		"sha256:e3b0c44298fc":                   "sha256:e3b0c44298fc",
+	}
+	for name, expected := range tests {
+		rewritten, err := rewrites.rewrite(name)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, rewritten, name)
+	}
+}
+
+func TestNewImageRewrites_GivenDockerHubAlias_NormalizesRegistry(t *testing.T) {
+	for _, registry := range []string{"docker.io", "index.docker.io", "registry-1.docker.io/", "Docker.IO"} {
+		rewrites, err := newImageRewrites(map[string]string{registry: "mirror.example.com/hub"}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"docker.io": "mirror.example.com/hub"}, rewrites.mirrors, registry)
+	}
+}
+
+func TestNewImageRewrites_GivenInvalidRules_ReturnsError(t *testing.T) {
+	tests := map[string]struct {
+		mirrors map[string]string
+		tags    map[string]string
+	}{
+		"invalid image":      {tags: map[string]string{"UPPER:case": "1"}},
+		"invalid tag":        {tags: map[string]string{"postgres:16": "not a tag"}},
+		"digest image":       {tags: map[string]string{"postgres@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855": "16"}},
+		"colliding images":   {tags: map[string]string{"postgres": "16", "docker.io/library/postgres:latest": "17"}},
+		"registry with path": {mirrors: map[string]string{"docker.io/library": "mirror.example.com"}},
+		"invalid mirror":     {mirrors: map[string]string{"docker.io": "Mirror Example"}},
+		"colliding mirrors":  {mirrors: map[string]string{"docker.io": "a.example.com", "index.docker.io": "b.example.com"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newImageRewrites(test.mirrors, test.tags)
+
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestNewClientFromDocker_GivenInvalidRewrites_ReturnsError(t *testing.T) {
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://127.0.0.1:2375"))
+	assert.Nil(t, err)
+	defer cli.Close()
+
+	_, err = NewClientFromDocker(cli, options.WithTagRewrites(map[string]string{"postgres": "16", "postgres:latest": "17"}))
+
+	assert.ErrorContains(t, err, "more than one tag rewrite")
+}
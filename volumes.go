@@ -0,0 +1,120 @@
+package dockerclient
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	"github.com/james226/dockerclient/options"
+)
+
+type Volume struct {
+	Name       string
+	Driver     string
+	Mountpoint string
+	Labels     map[string]string
+	CreatedAt  string
+}
+
+// VolumePruneReport describes the volumes removed by VolumeOperations.Prune.
+type VolumePruneReport struct {
+	VolumesDeleted []string
+	SpaceReclaimed uint64
+}
+
+type VolumeOperations struct {
+	cli *client.Client
+}
+
+// Create is used to create a named volume. If a volume with the same name
+// already exists, the existing volume is returned.
+func (v VolumeOperations) Create(ctx context.Context, name string, opts ...*options.CreateVolumeOptions) (*Volume, error) {
+	opt := options.CreateVolume()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	vol, err := v.cli.VolumeCreate(ctx, volume.CreateOptions{
+		Name:       name,
+		Driver:     opt.Driver(),
+		DriverOpts: opt.DriverOpts(),
+		Labels:     opt.Labels(),
+	})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	return toVolume(vol), nil
+}
+
+// Get is used to inspect the volume with the given name.
+func (v VolumeOperations) Get(ctx context.Context, name string) (*Volume, error) {
+	vol, err := v.cli.VolumeInspect(ctx, name)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	return toVolume(vol), nil
+}
+
+// List is used to list the volumes which have all of the given labels. If no
+// labels are given, all volumes are returned.
+func (v VolumeOperations) List(ctx context.Context, labels map[string]string) ([]*Volume, error) {
+	resp, err := v.cli.VolumeList(ctx, volume.ListOptions{
+		Filters: labelFilters(labels),
+	})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	volumes := make([]*Volume, 0, len(resp.Volumes))
+	for _, vol := range resp.Volumes {
+		volumes = append(volumes, toVolume(*vol))
+	}
+	return volumes, nil
+}
+
+// Remove is used to remove the volume with the given name. If force is true,
+// the volume is removed even when it is in use by a container.
+func (v VolumeOperations) Remove(ctx context.Context, name string, force bool) error {
+	return dockerError(v.cli.VolumeRemove(ctx, name, force))
+}
+
+// Prune is used to remove the unused anonymous volumes which have all of the
+// given labels, the same as "docker volume prune". Named volumes are only
+// removed when configured with options.PruneVolumes().WithAll().
+func (v VolumeOperations) Prune(ctx context.Context, labels map[string]string, opts ...*options.PruneVolumesOptions) (*VolumePruneReport, error) {
+	opt := options.PruneVolumes()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	args := labelFilters(labels)
+	if opt.All() {
+		args.Add("all", "true")
+	}
+	report, err := v.cli.VolumesPrune(ctx, args)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	return &VolumePruneReport{
+		VolumesDeleted: report.VolumesDeleted,
+		SpaceReclaimed: report.SpaceReclaimed,
+	}, nil
+}
+
+func toVolume(vol volume.Volume) *Volume {
+	return &Volume{
+		Name:       vol.Name,
+		Driver:     vol.Driver,
+		Mountpoint: vol.Mountpoint,
+		Labels:     vol.Labels,
+		CreatedAt:  vol.CreatedAt,
+	}
+}
+
+// Used to build a filter matching resources which have all of the given labels.
+func labelFilters(labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+	for name, value := range labels {
+		args.Add("label", name+"="+value)
+	}
+	return args
+}
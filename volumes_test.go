@@ -0,0 +1,40 @@
+package dockerclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/options"
+)
+
+func TestVolumePrune_GivenOptions_SendsFilters(t *testing.T) {
+	tests := map[string]struct {
+		opts     []*options.PruneVolumesOptions
+		expected string
+	}{
+		"anonymous only": {expected: `{"label":{"team=core":true}}`},
+		"all":            {opts: []*options.PruneVolumesOptions{options.PruneVolumes().WithAll()}, expected: `{"all":{"true":true},"label":{"team=core":true}}`},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("filters")
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"VolumesDeleted": ["data"], "SpaceReclaimed": 2048}`))
+			}))
+			defer server.Close()
+			c := newTestClient(t, server)
+
+			report, err := c.Volumes.Prune(context.Background(), map[string]string{"team": "core"}, test.opts...)
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, test.expected, query)
+			assert.Equal(t, &VolumePruneReport{VolumesDeleted: []string{"data"}, SpaceReclaimed: 2048}, report)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package dockerclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/james226/dockerclient/wait"
+)
+
+// The number of log lines included in the error returned when a container
+// fails to become ready.
+const readinessLogTail = "50"
+
+// waitTarget adapts a Container to the wait.StrategyTarget interface.
+type waitTarget struct {
+	container *Container
+}
+
+func (t waitTarget) Host(_ context.Context) (string, error) {
+	return daemonHost(t.container.cli)
+}
+
+func (t waitTarget) MappedPort(ctx context.Context, port nat.Port) (nat.Port, error) {
+	hostPort, err := t.container.MappedPort(ctx, uint16(port.Int()), port.Proto())
+	if err != nil {
+		return "", err
+	}
+	return nat.NewPort(port.Proto(), strconv.Itoa(int(hostPort)))
+}
+
+func (t waitTarget) Logs(ctx context.Context, from wait.LogPosition) ([]byte, wait.LogPosition, error) {
+	logsOptions := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+	}
+	if !from.Time.IsZero() {
+		logsOptions.Since = from.Time.Format(time.RFC3339Nano)
+	}
+	out, err := t.container.cli.ContainerLogs(ctx, t.container.ID, logsOptions)
+	if err != nil {
+		return nil, from, dockerError(err)
+	}
+	defer out.Close()
+	buf := new(bytes.Buffer)
+	_, err = stdcopy.StdCopy(buf, buf, out)
+	if err != nil {
+		return nil, from, err
+	}
+	return logsFrom(buf.Bytes(), from)
+}
+
+func (t waitTarget) Exec(ctx context.Context, cmd []string) (int, []byte, error) {
+	result, err := t.container.Exec(ctx, cmd)
+	if err != nil {
+		return 0, nil, err
+	}
+	return result.ExitCode, append(result.Stdout, result.Stderr...), nil
+}
+
+func (t waitTarget) State(ctx context.Context) (*container.State, error) {
+	data, err := t.container.cli.ContainerInspect(ctx, t.container.ID)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	return data.State, nil
+}
+
+// Used to get the address of the host which published container ports are
+// reachable on, based on the daemon the client is connected to.
+func daemonHost(cli *client.Client) (string, error) {
+	u, err := client.ParseHostURL(cli.DaemonHost())
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "unix", "npipe":
+		return "localhost", nil
+	default:
+		return u.Hostname(), nil
+	}
+}
+
+// Used to remove the timestamp which the daemon prefixes to each line of the
+// logs. The daemon also returns the lines written at the same time as from, so
+// the lines already read at that time are dropped. The position of the last
+// line is returned, or from if there are none.
+func logsFrom(logs []byte, from wait.LogPosition) ([]byte, wait.LogPosition, error) {
+	next := from
+	skip := from.Lines
+	out := new(bytes.Buffer)
+	for line := range bytes.Lines(logs) {
+		stamp, text, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			// Empty lines are only a timestamp.
+			stamp, text = bytes.TrimSuffix(line, []byte("\n")), []byte("\n")
+		}
+		written, err := time.Parse(time.RFC3339Nano, string(stamp))
+		if err != nil {
+			return nil, from, fmt.Errorf("failed to parse log timestamp: %v", err)
+		}
+		if written.Before(from.Time) {
+			continue
+		}
+		if written.Equal(from.Time) && skip > 0 {
+			skip--
+			continue
+		}
+		out.Write(text)
+		if written.Equal(next.Time) {
+			next.Lines++
+		} else if written.After(next.Time) {
+			next = wait.LogPosition{Time: written, Lines: 1}
+		}
+	}
+	return out.Bytes(), next, nil
+}
+
+// Used to read the combined stdout and stderr of a container, limited to the
+// given number of trailing lines.
+func containerLogs(ctx context.Context, cli *client.Client, containerID, tail string) ([]byte, error) {
+	out, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+	})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	defer out.Close()
+	buf := new(bytes.Buffer)
+	_, err = stdcopy.StdCopy(buf, buf, out)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// AllStrategy waits until every one of its strategies has passed.
+type AllStrategy struct {
+	strategies []Strategy
+	timeout    time.Duration
+}
+
+// ForAll returns a strategy which runs each of the given strategies in order,
+// waiting until all of them have passed.
+func ForAll(strategies ...Strategy) *AllStrategy {
+	return &AllStrategy{
+		strategies: strategies,
+		timeout:    DefaultTimeout,
+	}
+}
+
+// WithTimeout is used to configure how long to wait for all of the strategies
+// to pass. Each strategy is still limited by its own timeout.
+func (s *AllStrategy) WithTimeout(timeout time.Duration) *AllStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WaitUntilReady implements Strategy.
+func (s *AllStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	for _, strategy := range s.strategies {
+		err := strategy.WaitUntilReady(ctx, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnyStrategy waits until one of its strategies has passed.
+type AnyStrategy struct {
+	strategies []Strategy
+	timeout    time.Duration
+}
+
+// ForAny returns a strategy which runs each of the given strategies concurrently,
+// waiting until one of them has passed.
+func ForAny(strategies ...Strategy) *AnyStrategy {
+	return &AnyStrategy{
+		strategies: strategies,
+		timeout:    DefaultTimeout,
+	}
+}
+
+// WithTimeout is used to configure how long to wait for one of the strategies
+// to pass. Each strategy is still limited by its own timeout.
+func (s *AnyStrategy) WithTimeout(timeout time.Duration) *AnyStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WaitUntilReady implements Strategy. If none of the strategies pass, the
+// errors of all of them are returned.
+func (s *AnyStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	if len(s.strategies) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	results := make(chan error, len(s.strategies))
+	for _, strategy := range s.strategies {
+		go func(strategy Strategy) {
+			results <- strategy.WaitUntilReady(ctx, target)
+		}(strategy)
+	}
+	errs := make([]error, 0, len(s.strategies))
+	for range s.strategies {
+		err := <-results
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExecStrategy waits until a command run inside of the container succeeds.
+type ExecStrategy struct {
+	cmd          []string
+	exitCode     int
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// ForExec returns a strategy which waits until the given command exits with
+// a zero exit code when run inside of the container.
+func ForExec(cmd []string) *ExecStrategy {
+	return &ExecStrategy{
+		cmd:          cmd,
+		timeout:      DefaultTimeout,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// WithExitCode is used to configure the exit code expected from the command.
+func (s *ExecStrategy) WithExitCode(code int) *ExecStrategy {
+	s.exitCode = code
+	return s
+}
+
+// WithTimeout is used to configure how long to wait for before giving up.
+func (s *ExecStrategy) WithTimeout(timeout time.Duration) *ExecStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WithPollInterval is used to configure the time to wait between each check.
+func (s *ExecStrategy) WithPollInterval(interval time.Duration) *ExecStrategy {
+	s.pollInterval = interval
+	return s
+}
+
+// WaitUntilReady implements Strategy.
+func (s *ExecStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	return poll(ctx, s.timeout, s.pollInterval, func(ctx context.Context) error {
+		code, output, err := target.Exec(ctx, s.cmd)
+		if err != nil {
+			return err
+		}
+		if code != s.exitCode {
+			return fmt.Errorf("command '%s' exited with code %d: %s",
+				strings.Join(s.cmd, " "), code, strings.TrimSpace(string(output)))
+		}
+		return nil
+	})
+}
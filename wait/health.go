@@ -0,0 +1,54 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// HealthStrategy waits until Docker reports the container as healthy.
+type HealthStrategy struct {
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// ForHealthCheck returns a strategy which waits until the healthcheck configured
+// on the container's image reports the container as healthy.
+func ForHealthCheck() *HealthStrategy {
+	return &HealthStrategy{
+		timeout:      DefaultTimeout,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// WithTimeout is used to configure how long to wait for before giving up.
+func (s *HealthStrategy) WithTimeout(timeout time.Duration) *HealthStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WithPollInterval is used to configure the time to wait between each check.
+func (s *HealthStrategy) WithPollInterval(interval time.Duration) *HealthStrategy {
+	s.pollInterval = interval
+	return s
+}
+
+// WaitUntilReady implements Strategy.
+func (s *HealthStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	return poll(ctx, s.timeout, s.pollInterval, func(ctx context.Context) error {
+		state, err := target.State(ctx)
+		if err != nil {
+			return err
+		}
+		if state.Health == nil || state.Health.Status == container.NoHealthcheck {
+			return &permanentError{errors.New("container does not have a healthcheck configured")}
+		}
+		if state.Health.Status != container.Healthy {
+			return fmt.Errorf("container health is '%s'", state.Health.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,143 @@
+package wait
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// HTTPStrategy waits until an HTTP endpoint of the container responds with
+// the expected status code and body.
+type HTTPStrategy struct {
+	path          string
+	port          nat.Port
+	method        string
+	tls           bool
+	statusMatcher func(status int) bool
+	bodyPattern   *regexp.Regexp
+	timeout       time.Duration
+	pollInterval  time.Duration
+}
+
+// ForHTTP returns a strategy which waits until a GET request to the given path
+// responds with a 200 status code. The container port to send the request to
+// must be configured with WithPort.
+func ForHTTP(path string) *HTTPStrategy {
+	return &HTTPStrategy{
+		path:   path,
+		method: http.MethodGet,
+		statusMatcher: func(status int) bool {
+			return status == http.StatusOK
+		},
+		timeout:      DefaultTimeout,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// WithPort is used to configure the container port, in the format of "8080/tcp",
+// to send the request to.
+func (s *HTTPStrategy) WithPort(port nat.Port) *HTTPStrategy {
+	s.port = port
+	return s
+}
+
+// WithMethod is used to configure the HTTP method of the request.
+func (s *HTTPStrategy) WithMethod(method string) *HTTPStrategy {
+	s.method = method
+	return s
+}
+
+// WithTLS is used to send the request over HTTPS. Certificates are not verified.
+func (s *HTTPStrategy) WithTLS() *HTTPStrategy {
+	s.tls = true
+	return s
+}
+
+// WithStatusCode is used to configure the expected response status code.
+func (s *HTTPStrategy) WithStatusCode(status int) *HTTPStrategy {
+	return s.WithStatusCodeMatcher(func(actual int) bool {
+		return actual == status
+	})
+}
+
+// WithStatusCodeMatcher is used to configure a function which decides whether
+// the response status code is acceptable.
+func (s *HTTPStrategy) WithStatusCodeMatcher(matcher func(status int) bool) *HTTPStrategy {
+	s.statusMatcher = matcher
+	return s
+}
+
+// WithBody is used to configure a regular expression which the response body
+// must match. It panics if the pattern does not compile.
+func (s *HTTPStrategy) WithBody(pattern string) *HTTPStrategy {
+	s.bodyPattern = regexp.MustCompile(pattern)
+	return s
+}
+
+// WithTimeout is used to configure how long to wait for before giving up.
+func (s *HTTPStrategy) WithTimeout(timeout time.Duration) *HTTPStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WithPollInterval is used to configure the time to wait between each check.
+func (s *HTTPStrategy) WithPollInterval(interval time.Duration) *HTTPStrategy {
+	s.pollInterval = interval
+	return s
+}
+
+// WaitUntilReady implements Strategy.
+func (s *HTTPStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	if s.port == "" {
+		return fmt.Errorf("unable to wait for http endpoint '%s': no port configured", s.path)
+	}
+	host, err := target.Host(ctx)
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	httpClient := &http.Client{Timeout: time.Second}
+	if s.tls {
+		scheme = "https"
+		httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return poll(ctx, s.timeout, s.pollInterval, func(ctx context.Context) error {
+		port, err := target.MappedPort(ctx, s.port)
+		if err != nil {
+			return err
+		}
+		url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, port.Port()), s.path)
+		req, err := http.NewRequestWithContext(ctx, s.method, url, nil)
+		if err != nil {
+			return &permanentError{err}
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if !s.statusMatcher(resp.StatusCode) {
+			return fmt.Errorf("%s %s responded with unexpected status %d", s.method, url, resp.StatusCode)
+		}
+		if s.bodyPattern == nil {
+			return nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !s.bodyPattern.Match(body) {
+			return fmt.Errorf("%s %s responded with a body not matching '%s'", s.method, url, s.bodyPattern)
+		}
+		return nil
+	})
+}
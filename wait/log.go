@@ -0,0 +1,68 @@
+package wait
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// LogStrategy waits until a pattern appears in the container's logs.
+type LogStrategy struct {
+	pattern      *regexp.Regexp
+	occurrences  int
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// ForLog returns a strategy which waits until a line of the container's logs
+// matches the given regular expression. It panics if the pattern does not compile.
+func ForLog(pattern string) *LogStrategy {
+	return &LogStrategy{
+		pattern:      regexp.MustCompile(pattern),
+		occurrences:  1,
+		timeout:      DefaultTimeout,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// WithOccurrences is used to configure how many times the pattern must match
+// before the container is considered ready.
+func (s *LogStrategy) WithOccurrences(n int) *LogStrategy {
+	s.occurrences = n
+	return s
+}
+
+// WithTimeout is used to configure how long to wait for before giving up.
+func (s *LogStrategy) WithTimeout(timeout time.Duration) *LogStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WithPollInterval is used to configure the time to wait between each check.
+func (s *LogStrategy) WithPollInterval(interval time.Duration) *LogStrategy {
+	s.pollInterval = interval
+	return s
+}
+
+// WaitUntilReady implements Strategy. Each check only reads the output written
+// since the previous check, so the pattern is matched against each line once.
+func (s *LogStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	var position LogPosition
+	matches := 0
+	return poll(ctx, s.timeout, s.pollInterval, func(ctx context.Context) error {
+		logs, next, err := target.Logs(ctx, position)
+		if err != nil {
+			return err
+		}
+		position = next
+		for line := range bytes.Lines(logs) {
+			matches += len(s.pattern.FindAllIndex(line, -1))
+		}
+		if matches < s.occurrences {
+			return fmt.Errorf("log pattern '%s' matched %d of %d times", s.pattern, matches, s.occurrences)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,63 @@
+package wait
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// PortStrategy waits until a TCP port of the container accepts connections
+// from the host.
+type PortStrategy struct {
+	port         nat.Port
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// ForListeningPort returns a strategy which waits until the given container port,
+// in the format of "8080/tcp", accepts connections on its published host port.
+func ForListeningPort(port nat.Port) *PortStrategy {
+	return &PortStrategy{
+		port:         port,
+		timeout:      DefaultTimeout,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// WithTimeout is used to configure how long to wait for before giving up.
+func (s *PortStrategy) WithTimeout(timeout time.Duration) *PortStrategy {
+	s.timeout = timeout
+	return s
+}
+
+// WithPollInterval is used to configure the time to wait between each check.
+func (s *PortStrategy) WithPollInterval(interval time.Duration) *PortStrategy {
+	s.pollInterval = interval
+	return s
+}
+
+// WaitUntilReady implements Strategy.
+func (s *PortStrategy) WaitUntilReady(ctx context.Context, target StrategyTarget) error {
+	if s.port.Proto() != "tcp" {
+		return fmt.Errorf("unable to wait for port %s: only tcp ports are supported", s.port)
+	}
+	host, err := target.Host(ctx)
+	if err != nil {
+		return err
+	}
+	return poll(ctx, s.timeout, s.pollInterval, func(ctx context.Context) error {
+		port, err := target.MappedPort(ctx, s.port)
+		if err != nil {
+			return err
+		}
+		dialer := net.Dialer{Timeout: time.Second}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port.Port()))
+		if err != nil {
+			return fmt.Errorf("port %s is not listening: %v", s.port, err)
+		}
+		return conn.Close()
+	})
+}
@@ -0,0 +1,103 @@
+// Package wait provides strategies used to block until a started container is
+// ready to accept work.
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+const (
+	// DefaultTimeout is the time a strategy waits for before giving up, when
+	// no timeout has been configured.
+	DefaultTimeout = 60 * time.Second
+
+	// DefaultPollInterval is the time a strategy waits between each check, when
+	// no poll interval has been configured.
+	DefaultPollInterval = 100 * time.Millisecond
+)
+
+// Strategy is implemented by each of the readiness checks in this package.
+type Strategy interface {
+	// WaitUntilReady blocks until the target is ready, returning an error if
+	// the target does not become ready in time.
+	WaitUntilReady(ctx context.Context, target StrategyTarget) error
+}
+
+// StrategyTarget is the view of a running container which strategies use to
+// check for readiness.
+type StrategyTarget interface {
+	// Host returns the address of the host the container's ports are published on.
+	Host(ctx context.Context) (string, error)
+
+	// MappedPort returns the host port the given container port is published on.
+	MappedPort(ctx context.Context, port nat.Port) (nat.Port, error)
+
+	// Logs returns the combined stdout and stderr output of the container
+	// written after from, or all of its output when from is zero, with the
+	// position of the last returned line. Strategies pass that position back
+	// as from on their next check, so each check only reads new output.
+	Logs(ctx context.Context, from LogPosition) ([]byte, LogPosition, error)
+
+	// Exec runs the command inside of the container, returning its exit code and
+	// combined output.
+	Exec(ctx context.Context, cmd []string) (int, []byte, error)
+
+	// State returns the current state of the container.
+	State(ctx context.Context) (*container.State, error)
+}
+
+// LogPosition marks how much of a container's logs have been read. Lines can
+// share a timestamp, so the number of lines read with the last timestamp is
+// kept to skip only those lines when the logs are next read.
+type LogPosition struct {
+	// Time is when the last line read was written.
+	Time time.Time
+	// Lines is the number of lines read which were written at Time.
+	Lines int
+}
+
+// Used to mark an error as not recoverable by further polling.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Used to repeatedly invoke check every interval until it succeeds, it returns
+// a permanent error, the timeout expires or ctx is done. The last check error is
+// included in the returned error to help explain why the target never became
+// ready.
+func poll(parent context.Context, timeout, interval time.Duration, check func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		select {
+		case <-ctx.Done():
+			if parent.Err() != nil {
+				return fmt.Errorf("stopped waiting: %w (last error: %v)", parent.Err(), err)
+			}
+			return fmt.Errorf("timed out after %s: %w", timeout, err)
+		case <-time.After(interval):
+		}
+	}
+}
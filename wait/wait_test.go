@@ -0,0 +1,235 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTarget struct {
+	host     string
+	ports    map[nat.Port]nat.Port
+	logs     func() []string
+	exitCode func() int
+	state    func() *container.State
+}
+
+func (t *fakeTarget) Host(_ context.Context) (string, error) {
+	return t.host, nil
+}
+
+func (t *fakeTarget) MappedPort(_ context.Context, port nat.Port) (nat.Port, error) {
+	mapped, ok := t.ports[port]
+	if !ok {
+		return "", fmt.Errorf("port %s is not published", port)
+	}
+	return mapped, nil
+}
+
+// Each line of the fake logs is written at the same time, so that reading
+// from a position relies on the number of lines read at that time.
+func (t *fakeTarget) Logs(_ context.Context, from LogPosition) ([]byte, LogPosition, error) {
+	var logs []byte
+	lines := t.logs()
+	for _, line := range lines[min(from.Lines, len(lines)):] {
+		logs = append(logs, line+"\n"...)
+	}
+	return logs, LogPosition{Time: time.Unix(1, 0), Lines: len(lines)}, nil
+}
+
+func (t *fakeTarget) Exec(_ context.Context, _ []string) (int, []byte, error) {
+	return t.exitCode(), []byte("output"), nil
+}
+
+func (t *fakeTarget) State(_ context.Context) (*container.State, error) {
+	return t.state(), nil
+}
+
+func TestForLog_WhenPatternAppears_ReturnsNil(t *testing.T) {
+	calls := 0
+	target := &fakeTarget{logs: func() []string {
+		calls++
+		if calls < 3 {
+			return []string{"starting"}
+		}
+		return []string{"starting", "ready to accept connections"}
+	}}
+
+	err := ForLog("ready to accept").WithPollInterval(time.Millisecond).WaitUntilReady(context.Background(), target)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestForLog_WithOccurrences_WaitsForAllMatches(t *testing.T) {
+	target := &fakeTarget{logs: func() []string {
+		return []string{"ready"}
+	}}
+
+	err := ForLog("ready").
+		WithOccurrences(2).
+		WithTimeout(20*time.Millisecond).
+		WithPollInterval(time.Millisecond).
+		WaitUntilReady(context.Background(), target)
+	assert.ErrorContains(t, err, "matched 1 of 2 times")
+}
+
+func TestForLog_WithOccurrences_CountsMatchesAcrossChecks(t *testing.T) {
+	calls := 0
+	target := &fakeTarget{logs: func() []string {
+		calls++
+		if calls < 2 {
+			return []string{"ready"}
+		}
+		return []string{"ready", "ready"}
+	}}
+
+	err := ForLog("ready").WithOccurrences(2).WithPollInterval(time.Millisecond).WaitUntilReady(context.Background(), target)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestForLog_WhenContextCancelled_ReturnsCancellationError(t *testing.T) {
+	target := &fakeTarget{logs: func() []string {
+		return nil
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := ForLog("ready").WithPollInterval(time.Millisecond).WaitUntilReady(ctx, target)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, err.Error(), "timed out after")
+}
+
+func TestForListeningPort_WhenPortAcceptsConnections_ReturnsNil(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	target := &fakeTarget{
+		host:  "127.0.0.1",
+		ports: map[nat.Port]nat.Port{"5432/tcp": nat.Port(port + "/tcp")},
+	}
+
+	err = ForListeningPort("5432/tcp").WaitUntilReady(context.Background(), target)
+	assert.Nil(t, err)
+}
+
+func TestForListeningPort_GivenUdpPort_ReturnsError(t *testing.T) {
+	err := ForListeningPort("53/udp").WaitUntilReady(context.Background(), &fakeTarget{})
+	assert.ErrorContains(t, err, "only tcp ports are supported")
+}
+
+func TestForHTTP_WhenResponseMatches_ReturnsNil(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/health", r.URL.Path)
+		w.Write([]byte(`{"status":"up"}`))
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	target := &fakeTarget{
+		host:  host,
+		ports: map[nat.Port]nat.Port{"8080/tcp": nat.Port(port + "/tcp")},
+	}
+
+	err := ForHTTP("/health").
+		WithPort("8080/tcp").
+		WithBody(`"status":"up"`).
+		WaitUntilReady(context.Background(), target)
+	assert.Nil(t, err)
+}
+
+func TestForHTTP_WhenStatusDoesNotMatch_ReturnsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	target := &fakeTarget{
+		host:  host,
+		ports: map[nat.Port]nat.Port{"8080/tcp": nat.Port(port + "/tcp")},
+	}
+
+	err := ForHTTP("/").
+		WithPort("8080/tcp").
+		WithTimeout(20*time.Millisecond).
+		WaitUntilReady(context.Background(), target)
+	assert.ErrorContains(t, err, "unexpected status 503")
+}
+
+func TestForHTTP_WithoutPort_ReturnsError(t *testing.T) {
+	err := ForHTTP("/").WaitUntilReady(context.Background(), &fakeTarget{})
+	assert.ErrorContains(t, err, "no port configured")
+}
+
+func TestForExec_WhenCommandSucceeds_ReturnsNil(t *testing.T) {
+	calls := 0
+	target := &fakeTarget{exitCode: func() int {
+		calls++
+		if calls < 2 {
+			return 1
+		}
+		return 0
+	}}
+
+	err := ForExec([]string{"pg_isready"}).WithPollInterval(time.Millisecond).WaitUntilReady(context.Background(), target)
+	assert.Nil(t, err)
+}
+
+func TestForHealthCheck_WhenHealthy_ReturnsNil(t *testing.T) {
+	target := &fakeTarget{state: func() *container.State {
+		return &container.State{Health: &container.Health{Status: container.Healthy}}
+	}}
+
+	err := ForHealthCheck().WaitUntilReady(context.Background(), target)
+	assert.Nil(t, err)
+}
+
+func TestForHealthCheck_WithoutHealthcheck_ReturnsErrorImmediately(t *testing.T) {
+	target := &fakeTarget{state: func() *container.State {
+		return &container.State{}
+	}}
+
+	start := time.Now()
+	err := ForHealthCheck().WaitUntilReady(context.Background(), target)
+	assert.ErrorContains(t, err, "does not have a healthcheck")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+type staticStrategy struct {
+	err error
+}
+
+func (s staticStrategy) WaitUntilReady(_ context.Context, _ StrategyTarget) error {
+	return s.err
+}
+
+func TestForAll_WhenOneFails_ReturnsError(t *testing.T) {
+	failure := errors.New("failed")
+
+	err := ForAll(staticStrategy{}, staticStrategy{failure}).WaitUntilReady(context.Background(), &fakeTarget{})
+	assert.ErrorIs(t, err, failure)
+}
+
+func TestForAny_WhenOnePasses_ReturnsNil(t *testing.T) {
+	err := ForAny(staticStrategy{errors.New("failed")}, staticStrategy{}).WaitUntilReady(context.Background(), &fakeTarget{})
+	assert.Nil(t, err)
+}
+
+func TestForAny_WhenAllFail_ReturnsAllErrors(t *testing.T) {
+	first := errors.New("first")
+	second := errors.New("second")
+
+	err := ForAny(staticStrategy{first}, staticStrategy{second}).WaitUntilReady(context.Background(), &fakeTarget{})
+	assert.ErrorIs(t, err, first)
+	assert.ErrorIs(t, err, second)
+}
@@ -0,0 +1,46 @@
+package dockerclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/james226/dockerclient/wait"
+)
+
+func TestLogsFrom_GivenTimestampedLogs_ReturnsLinesAfterPosition(t *testing.T) {
+	logs := []byte("2024-05-01T10:00:00.000000001Z starting\n" +
+		"2024-05-01T10:00:00.000000002Z\n" +
+		"2024-05-01T10:00:01.5Z ready to accept connections\n")
+	from := wait.LogPosition{Time: time.Date(2024, 5, 1, 10, 0, 0, 1, time.UTC), Lines: 1}
+
+	out, next, err := logsFrom(logs, from)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "\nready to accept connections\n", string(out))
+	assert.Equal(t, wait.LogPosition{Time: time.Date(2024, 5, 1, 10, 0, 1, 500000000, time.UTC), Lines: 1}, next)
+}
+
+func TestLogsFrom_GivenLinesSharingLastTimestamp_ReturnsUnreadLines(t *testing.T) {
+	logs := []byte("2024-05-01T10:00:00Z starting\n" +
+		"2024-05-01T10:00:00Z listening\n" +
+		"2024-05-01T10:00:00Z ready\n")
+	from := wait.LogPosition{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Lines: 1}
+
+	out, next, err := logsFrom(logs, from)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "listening\nready\n", string(out))
+	assert.Equal(t, wait.LogPosition{Time: from.Time, Lines: 3}, next)
+}
+
+func TestLogsFrom_GivenNoNewLines_ReturnsPosition(t *testing.T) {
+	from := wait.LogPosition{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Lines: 2}
+
+	out, next, err := logsFrom(nil, from)
+
+	assert.Nil(t, err)
+	assert.Empty(t, out)
+	assert.Equal(t, from, next)
+}